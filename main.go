@@ -2,39 +2,35 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
-)
 
-var defaultKeySource = []byte{
-	0xca, 0xd0, 0xb0, 0x1b, 0xfe, 0x3f, 0x3f, 0xb6,
-	0xca, 0xc0, 0xbd, 0x8f, 0x40, 0xf0, 0xee, 0x85,
-	0x6e, 0xe1, 0xda, 0x7a, 0xef, 0xb4, 0xd4, 0xbb,
-	0x6a, 0xd8, 0x4b, 0x84, 0x26, 0x99, 0x6f, 0xfd,
-	0x65, 0x97, 0xf2, 0x5f, 0xa4, 0x46, 0xdb, 0x47,
-	0x88, 0x63, 0x4f, 0x2c, 0x14, 0x0b, 0x3c, 0xce,
-	0xaa, 0xc4, 0x5c, 0xe4, 0x15, 0x86, 0x26, 0x5c,
-	0x52, 0x3a, 0x80, 0xf8, 0xbe, 0x45, 0x40, 0x6a,
-	0x66, 0xb4, 0xc5, 0xf6, 0xd0, 0x12, 0xe0, 0x43,
-	0x44, 0x65, 0xc6, 0xe3, 0x9e, 0xf9, 0x43, 0x35,
-}
+	"github.com/askeladdk/ccmixar/mix"
+)
 
-func stringToGameID(s string) (gameID, error) {
+func stringToGameID(s string) (mix.Game, error) {
 	switch strings.ToLower(s) {
 	case "cc1":
-		return gameCC1, nil
+		return mix.GameCC1, nil
 	case "cc2":
-		return gameCC2, nil
+		return mix.GameCC2, nil
 	case "ra1":
-		return gameRA1, nil
+		return mix.GameRA1, nil
 	case "ra2":
-		return gameRA2, nil
+		return mix.GameRA2, nil
 	case "":
 		return 0, errors.New("no game specified")
 	default:
@@ -42,6 +38,343 @@ func stringToGameID(s string) (gameID, error) {
 	}
 }
 
+// openMixFile opens a mix file and detects its game if game is empty.
+func openMixFile(filename, game string, flag int) (*os.File, *mix.Reader, error) {
+	gameID := mix.GameCC1
+	if game != "" {
+		if g, err := stringToGameID(game); err != nil {
+			return nil, nil, err
+		} else {
+			gameID = g
+		}
+	}
+
+	f, err := os.OpenFile(filename, flag, 0)
+	if err != nil {
+		return nil, nil, err
+	} else if stat, err := f.Stat(); err != nil {
+		f.Close()
+		return nil, nil, err
+	} else if mixf, err := mix.NewReader(f, stat.Size(), gameID); err != nil {
+		f.Close()
+		return nil, nil, err
+	} else {
+		if mixf.DeclaredCount != len(mixf.Entries) {
+			fmt.Fprintf(os.Stderr, "warning: header declares %d entries but the index ends after %d entries\n", mixf.DeclaredCount, len(mixf.Entries))
+		}
+		if game == "" {
+			det := mixf.DetectGame()
+			mixf.Game = det.Game
+			fmt.Fprintf(os.Stderr, "detected game %s (%.0f%% confidence)\n", det.Game, 100*det.Confidence)
+		}
+		return f, mixf, nil
+	}
+}
+
+func parseKeySource(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	} else if ks, err := hex.DecodeString(s); err != nil {
+		return nil, fmt.Errorf("invalid key source: %v", err)
+	} else if len(ks) != 80 {
+		return nil, fmt.Errorf("invalid key source: expected 80 bytes but got %d", len(ks))
+	} else {
+		return ks, nil
+	}
+}
+
+// replaceFile writes a new version of the open file src to a temporary file in the same directory,
+// syncs it to disk, closes src and renames the temporary file over it.
+// If backup is true then the old version is kept in a file with the extension .bak.
+func replaceFile(src *os.File, backup bool, write func(w io.Writer) error) error {
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	filename := src.Name()
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	wb := bufio.NewWriter(tmp)
+	if err := write(wb); err != nil {
+		return err
+	} else if err := wb.Flush(); err != nil {
+		return err
+	} else if err := tmp.Chmod(stat.Mode()); err != nil {
+		return err
+	} else if err := tmp.Sync(); err != nil {
+		return err
+	} else if err := tmp.Close(); err != nil {
+		return err
+	} else if err := src.Close(); err != nil {
+		return err
+	} else if backup {
+		if err := backupFile(filename); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(filename))
+	return nil
+}
+
+// backupFile replaces the backup of a file with a hard link to it, or with a copy of it
+// if the file system does not support hard links.
+func backupFile(filename string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.bak")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := os.Remove(tmp.Name()); err != nil {
+		return err
+	} else if err := os.Link(filename, tmp.Name()); err != nil {
+		if tmp, err = os.OpenFile(tmp.Name(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644); err != nil {
+			return err
+		} else if src, err := os.Open(filename); err != nil {
+			return err
+		} else if _, err := io.Copy(tmp, src); err != nil {
+			src.Close()
+			return err
+		} else if err := src.Close(); err != nil {
+			return err
+		} else if err := tmp.Sync(); err != nil {
+			return err
+		} else if err := tmp.Close(); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), filename+".bak")
+}
+
+// restoreBackup renames the backup of a file over it.
+func restoreBackup(filename string) error {
+	if _, err := os.Stat(filename + ".bak"); err != nil {
+		return fmt.Errorf("no backup of %s", filename)
+	} else if err := os.Rename(filename+".bak", filename); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(filename))
+	return nil
+}
+
+// syncDir syncs a directory so that renames in it are on disk.
+// Errors are ignored because not every platform can sync directories.
+func syncDir(dirname string) {
+	if d, err := os.Open(dirname); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+// nameSources are the name databases given with -csv.
+// They are stacked per game on top of the embedded database and the user database directory.
+type nameSources struct {
+	csvs     stringList
+	dbs      map[mix.Game]*mix.NameDB
+	reported map[string]bool
+}
+
+// report prints an error of loading a database once.
+func (names *nameSources) report(err error) {
+	if !names.reported[err.Error()] {
+		names.reported[err.Error()] = true
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func nameSourcesFlag(cmd *flag.FlagSet) *nameSources {
+	names := &nameSources{dbs: map[mix.Game]*mix.NameDB{}, reported: map[string]bool{}}
+	cmd.Var(&names.csvs, "csv", "Path to mix database csv or XCC global mix database.dat. Can be repeated, and later databases take precedence.")
+	return names
+}
+
+// db returns the stack of name databases of a game, loading it the first time.
+// Databases that cannot be read are reported and skipped.
+func (names *nameSources) db(game mix.Game) *mix.NameDB {
+	if db, ok := names.dbs[game]; ok {
+		return db
+	}
+
+	db, err := mix.NewNameDB(game)
+	if err != nil {
+		names.report(err)
+		db = &mix.NameDB{Game: game}
+	}
+	if dir := userDatabaseDir(); dir != "" {
+		if err := db.LoadDir(dir); err != nil {
+			names.report(err)
+		}
+	}
+	for _, csv := range names.csvs {
+		if err := db.Load(csv); err != nil {
+			names.report(err)
+		}
+	}
+
+	names.dbs[game] = db
+	return db
+}
+
+// userDatabaseDir returns the directory of the user's name databases,
+// which is $CCMIXAR_DB or else ccmixar in the user's configuration directory.
+func userDatabaseDir() string {
+	if dir := os.Getenv("CCMIXAR_DB"); dir != "" {
+		return dir
+	} else if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "ccmixar")
+	}
+	return ""
+}
+
+// resolveNames names the entries using the stack of name databases and the local mix database
+// and reports the names that the sources disagree on.
+func resolveNames(mixf *mix.Reader, names *nameSources) {
+	mixf.RecoverLmd()
+	for _, c := range mixf.ResolveNames(names.db(mixf.Game)) {
+		fmt.Fprintf(os.Stderr, "conflict: %08X is %s in %s but %s in %s\n", c.ID, c.Name, c.Source, c.NewName, c.NewSource)
+	}
+}
+
+// harvestNames names the unnamed entries after the names that the ini files in the mix file
+// and in the mix files next to it refer to.
+func harvestNames(mixf *mix.Reader, filename string, names *nameSources) {
+	siblings, _ := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.mix"))
+
+	var sources []*mix.Reader
+	for _, sibling := range siblings {
+		if same, err := sameFile(sibling, filename); err != nil || same {
+			continue
+		} else if f, err := os.Open(sibling); err != nil {
+			continue
+		} else if fi, err := f.Stat(); err != nil {
+			f.Close()
+		} else if sub, err := mix.NewReader(f, fi.Size(), mixf.Game); err != nil {
+			f.Close()
+		} else {
+			defer f.Close()
+			// conflicts are only reported for the mix file itself
+			sub.RecoverLmd()
+			_ = sub.ResolveNames(names.db(sub.Game))
+			sources = append(sources, sub)
+		}
+	}
+
+	mixf.HarvestNames(sources...)
+}
+
+func sameFile(a, b string) (bool, error) {
+	if fa, err := os.Stat(a); err != nil {
+		return false, err
+	} else if fb, err := os.Stat(b); err != nil {
+		return false, err
+	} else {
+		return os.SameFile(fa, fb), nil
+	}
+}
+
+// entryFilename returns the name of an entry or its ID if it has no name.
+func entryFilename(entry mix.Entry) string {
+	if entry.Name == "" {
+		return fmt.Sprintf("%08X", entry.ID)
+	}
+	return entry.Name
+}
+
+// typedFilename returns the name of the i-th entry, or if it has no name,
+// its ID with the extension of the format that its contents are recognized as.
+func typedFilename(mixf *mix.Reader, i int) string {
+	entry := mixf.Entries[i]
+	if entry.Name == "" {
+		if ext := mixf.FileType(i); ext != "" {
+			return fmt.Sprintf("%08X.%s", entry.ID, ext)
+		}
+	}
+	return entryFilename(entry)
+}
+
+// Exit statuses of unpack and repair for mix files that were cut short.
+const (
+	exitIncomplete = 3 // incomplete entries were found and nothing was written
+	exitDropped    = 4 // incomplete entries were dropped
+	exitTruncated  = 5 // incomplete entries were truncated to the bytes present
+	exitPartial    = 6 // the bytes present of incomplete entries were extracted to .partial files
+)
+
+// exitError is an error that exits with a status other than 1.
+type exitError struct {
+	status int
+	msg    string
+}
+
+func (err *exitError) Error() string {
+	return err.msg
+}
+
+// salvageIncomplete removes the entries that were cut short if mode is drop
+// or shrinks them to the bytes present if mode is truncate.
+// Entries that have no bytes present are removed in either mode.
+func salvageIncomplete(mixf *mix.Reader, mode string) {
+	if mode != "drop" && mode != "truncate" {
+		return
+	}
+
+	incomplete := map[int]bool{}
+	for _, i := range mixf.Incomplete() {
+		incomplete[i] = true
+	}
+
+	entries := mixf.Entries[:0]
+	for i, entry := range mixf.Entries {
+		if incomplete[i] {
+			if mode == "drop" || mixf.Available(i) == 0 {
+				continue
+			}
+			entry.Size = mixf.Available(i)
+		}
+		entries = append(entries, entry)
+	}
+	mixf.Entries = entries
+}
+
+// checkIncomplete lists the entries of a mix file that were cut short on stderr
+// and returns the error to exit with after they are handled according to mode.
+// The error is nil if there are no incomplete entries.
+func checkIncomplete(mixf *mix.Reader, mode string) error {
+	incomplete := mixf.Incomplete()
+	if len(incomplete) == 0 {
+		return nil
+	}
+
+	for _, i := range incomplete {
+		entry := mixf.Entries[i]
+		fmt.Fprintf(os.Stderr, "incomplete: %04d %08X %s (%d of %d bytes)\n", i, entry.ID, entry.Name, mixf.Available(i), entry.Size)
+	}
+
+	n := len(incomplete)
+	switch mode {
+	case "drop":
+		return &exitError{exitDropped, fmt.Sprintf("%d incomplete entries were dropped", n)}
+	case "truncate":
+		return &exitError{exitTruncated, fmt.Sprintf("%d incomplete entries were truncated", n)}
+	case "partial":
+		return &exitError{exitPartial, fmt.Sprintf("%d incomplete entries were extracted partially", n)}
+	default:
+		return &exitError{exitIncomplete, fmt.Sprintf("%d incomplete entries; choose -incomplete drop, truncate or partial", n)}
+	}
+}
+
 func commandPack(args []string) error {
 	var (
 		cmd      = flag.NewFlagSet("pack", flag.ExitOnError)
@@ -50,7 +383,10 @@ func commandPack(args []string) error {
 		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2.")
 		checksum = cmd.Bool("checksum", false, "Compute checksum if game is not cc1.")
 		database = cmd.Bool("database", false, "Include local mix database.")
+		describe = cmd.Bool("descriptions", false, "Include the descriptions of the name databases in an entry of their own.")
+		names    = nameSourcesFlag(cmd)
 		encrypt  = cmd.Bool("encrypt", false, "Encrypt if game is not cc1.")
+		keysrc   = cmd.String("keysource", "", "Hex encoded 80 byte key source. Random if omitted.")
 	)
 
 	if err := cmd.Parse(args); err != nil {
@@ -69,12 +405,17 @@ func commandPack(args []string) error {
 		return errors.New("cannot output to the directory that is being packed")
 	}
 
+	keySource, err := parseKeySource(*keysrc)
+	if err != nil {
+		return err
+	}
+
 	flags := uint32(0)
 	if *checksum {
-		flags |= flagChecksum
+		flags |= mix.FlagChecksum
 	}
 	if *encrypt {
-		flags |= flagEncrypted
+		flags |= mix.FlagEncrypted
 	}
 
 	if gameID, err := stringToGameID(*game); err != nil {
@@ -83,12 +424,31 @@ func commandPack(args []string) error {
 		return err
 	} else {
 		defer f.Close()
-		files, err := listFilesToPack(absdirname, *database, gameID)
+		files, err := mix.ListFilesToPack(absdirname, false, gameID)
 		if err != nil {
 			return err
 		}
+		if *describe {
+			descriptions, err := writeDescriptions(gameID, files, names)
+			if err != nil {
+				return err
+			} else if descriptions != nil {
+				files = append(files, descriptions)
+			}
+		}
+		if *database {
+			lmd, err := mix.WriteLmd(gameID, files)
+			if err != nil {
+				return err
+			}
+			files = append(files, lmd)
+		}
 		wb := bufio.NewWriter(f)
-		if err := pack(wb, files, gameID, flags, defaultKeySource); err != nil {
+		w := mix.NewWriter(wb, gameID)
+		w.Flags = flags
+		w.KeySource = keySource
+		w.Add(files...)
+		if err := w.Close(); err != nil {
 			return err
 		} else if err := wb.Flush(); err != nil {
 			return err
@@ -98,13 +458,31 @@ func commandPack(args []string) error {
 	return nil
 }
 
+// writeDescriptions creates the descriptions entry of files with the descriptions of the name databases,
+// or returns nil if none of the files has a description.
+func writeDescriptions(game mix.Game, files []mix.File, names *nameSources) (mix.File, error) {
+	db := names.db(game)
+	fileID := mix.GetFileID(game)
+	descriptions := map[uint32]string{}
+	for _, f := range files {
+		id := fileID(f.Name())
+		if entry, ok := db.Lookup(id); ok {
+			descriptions[id] = entry.Description
+		}
+	}
+	return mix.WriteDescriptions(game, files, descriptions)
+}
+
 func commandUnpack(args []string) error {
 	var (
-		cmd      = flag.NewFlagSet("unpack", flag.ExitOnError)
-		filename = cmd.String("mix", "", "Path to .mix file.")
-		dirname  = cmd.String("dir", "", "Output directory.")
-		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2.")
-		gmd      = cmd.String("csv", "", "Path to mix database csv.")
+		cmd       = flag.NewFlagSet("unpack", flag.ExitOnError)
+		filename  = cmd.String("mix", "", "Path to .mix file.")
+		dirname   = cmd.String("dir", "", "Output directory.")
+		game      = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		names     = nameSourcesFlag(cmd)
+		recursive = cmd.Bool("recursive", false, "Unpack nested mix files to subdirectories.")
+		dups      = cmd.String("duplicates", "copy", "How to unpack entries with the same contents as another entry: skip, hardlink or copy.")
+		partial   = cmd.String("incomplete", "", "How to unpack entries that were cut short: drop, truncate or partial.")
 	)
 
 	if err := cmd.Parse(args); err != nil {
@@ -113,6 +491,10 @@ func commandUnpack(args []string) error {
 		return errors.New("no output directory specified")
 	} else if *filename == "" {
 		return errors.New("no mix file specified")
+	} else if *dups != "skip" && *dups != "hardlink" && *dups != "copy" {
+		return fmt.Errorf("invalid duplicates mode: %s", *dups)
+	} else if *partial != "" && *partial != "drop" && *partial != "truncate" && *partial != "partial" {
+		return fmt.Errorf("invalid incomplete mode: %s", *partial)
 	}
 
 	absdirname, _ := filepath.Abs(*dirname)
@@ -121,47 +503,96 @@ func commandUnpack(args []string) error {
 		return errors.New("cannot output to the same directory where the input mix file is located")
 	} else if err := os.MkdirAll(absdirname, os.ModePerm); err != nil {
 		return err
-	} else if gameID, err := stringToGameID(*game); err != nil {
-		return err
-	} else if f, err := os.Open(*filename); err != nil {
-		return err
-	} else if stat, err := f.Stat(); err != nil {
-		return err
-	} else if mix, err := unpackMixFile(io.NewSectionReader(f, 0, stat.Size()), gameID); err != nil {
+	} else if f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY); err != nil {
 		return err
 	} else {
 		defer f.Close()
 
-		_ = mix.ReadGmd(*gmd)
-		mix.RecoverLmd()
-		_ = mix.ReadLmd()
+		resolveNames(mixf, names)
+		harvestNames(mixf, *filename, names)
+
+		incompleteErr := checkIncomplete(mixf, *partial)
+		if *partial == "" && incompleteErr != nil {
+			return incompleteErr
+		} else if err := unpackMix(mixf, absdirname, names, *recursive, *dups, *partial); err != nil {
+			return err
+		}
+		return incompleteErr
+	}
+}
+
+// unpackMix writes the entries of a mix file to dirname.
+// Entries with the same offset and size as an earlier entry are skipped, hardlinked or copied depending on dups.
+// Entries that were cut short are dropped, truncated or written to .partial files depending on partial.
+func unpackMix(mixf *mix.Reader, dirname string, names *nameSources, recursive bool, dups, partial string) error {
+	aliases := mixf.Aliases()
+	incomplete := map[int]bool{}
+	for _, i := range mixf.Incomplete() {
+		incomplete[i] = true
+	}
+
+	// written are the paths of the entries that were written as files
+	written := map[int]string{}
+
+	for i := range mixf.Entries {
+		fname := filepath.Join(dirname, typedFilename(mixf, i))
+
+		orig := aliases[i].Original
+		if orig != -1 && dups == "skip" {
+			continue
+		} else if incomplete[i] && (partial == "drop" || mixf.Available(i) == 0) {
+			continue
+		} else if incomplete[i] && partial == "partial" {
+			fname += ".partial"
+		}
 
-		for i, entry := range mix.files {
-			infile := mix.OpenFile(i)
-			fname := entry.name
-			if fname == "" {
-				fname = fmt.Sprintf("%08X", entry.id)
+		if recursive {
+			if sub, err := mixf.OpenMix(i); err == nil {
+				resolveNames(sub, names)
+				sub.HarvestNames(mixf)
+				if err := os.MkdirAll(fname, os.ModePerm); err != nil {
+					return err
+				} else if err := unpackMix(sub, fname, names, true, dups, partial); err != nil {
+					return err
+				}
+				continue
 			}
-			fname = filepath.Join(absdirname, fname)
-			if outfile, err := os.OpenFile(fname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
-				return err
-			} else if _, err := io.Copy(outfile, infile); err != nil {
+		}
+
+		// duplicates are copied if their original was not written as a file
+		if target, ok := written[orig]; ok && orig != -1 && dups == "hardlink" && !incomplete[i] {
+			if target == fname {
+				continue
+			} else if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
 				return err
-			} else if err := outfile.Close(); err != nil {
+			} else if err := os.Link(target, fname); err != nil {
 				return err
 			}
+			written[i] = fname
+			continue
 		}
 
-		return nil
+		if outfile, err := os.OpenFile(fname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+			return err
+		} else if _, err := io.CopyN(outfile, mixf.OpenFile(i), int64(mixf.Available(i))); err != nil {
+			return err
+		} else if err := outfile.Close(); err != nil {
+			return err
+		}
+		written[i] = fname
 	}
+
+	return nil
 }
 
 func commandInfo(args []string) error {
 	var (
-		cmd      = flag.NewFlagSet("info", flag.ExitOnError)
-		filename = cmd.String("mix", "", "Path to .mix file.")
-		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2.")
-		gmd      = cmd.String("csv", "", "Path to mix database csv.")
+		cmd       = flag.NewFlagSet("info", flag.ExitOnError)
+		filename  = cmd.String("mix", "", "Path to .mix file.")
+		game      = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		names     = nameSourcesFlag(cmd)
+		recursive = cmd.Bool("recursive", false, "List the contents of nested mix files.")
+		asJSON    = cmd.Bool("json", false, "Print the information as JSON.")
 	)
 
 	if err := cmd.Parse(args); err != nil {
@@ -170,70 +601,868 @@ func commandInfo(args []string) error {
 		return errors.New("no mix file specified")
 	}
 
-	if gameID, err := stringToGameID(*game); err != nil {
-		return err
-	} else if f, err := os.Open(*filename); err != nil {
-		return err
-	} else if stat, err := f.Stat(); err != nil {
-		return err
-	} else if mix, err := unpackMixFile(io.NewSectionReader(f, 0, stat.Size()), gameID); err != nil {
+	if f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY); err != nil {
 		return err
 	} else {
 		defer f.Close()
 
-		_ = mix.ReadGmd(*gmd)
-		mix.RecoverLmd()
-		_ = mix.ReadLmd()
+		resolveNames(mixf, names)
+		harvestNames(mixf, *filename, names)
+
+		entries := listEntries(mixf, "", "", names, *recursive)
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(mixInfo{
+				Game:      mixf.Game.String(),
+				Checksum:  (mixf.Flags & mix.FlagChecksum) != 0,
+				Encrypted: (mixf.Flags & mix.FlagEncrypted) != 0,
+				Size:      mixf.BodySize,
+				Entries:   entries,
+			})
+		}
 
-		fmt.Printf("checksum   %t\n", (mix.flags&flagChecksum) != 0)
-		fmt.Printf("encrypted  %t\n", (mix.flags&flagEncrypted) != 0)
-		fmt.Printf("files      %d\n", len(mix.files))
-		fmt.Printf("size       %d bytes\n", mix.size)
+		fmt.Printf("game       %s\n", mixf.Game)
+		fmt.Printf("checksum   %t\n", (mixf.Flags&mix.FlagChecksum) != 0)
+		fmt.Printf("encrypted  %t\n", (mixf.Flags&mix.FlagEncrypted) != 0)
+		fmt.Printf("files      %d\n", len(mixf.Entries))
+		fmt.Printf("size       %d bytes\n", mixf.BodySize)
 
 		tw := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
-		fmt.Fprintf(tw, "index\tid\toffset\tlength\tname\n")
-		for i, entry := range mix.files {
-			fmt.Fprintf(tw, "%04d\t%08X\t%08X\t%d\t%s\n", i, entry.id, entry.offset, entry.size, entry.name)
+		fmt.Fprintf(tw, "index\tid\toffset\tlength\talias\ttype\tname\tdescription\tsource\n")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%08X\t%08X\t%d\t%s\t%s\t%s\t%s\t%s\n", e.Index, e.ID, e.Offset, e.Size, e.Alias, e.Type, e.Name, e.Description, e.Source)
 		}
-		tw.Flush()
+		return tw.Flush()
+	}
+}
 
-		return nil
+// mixInfo is the information that info prints as JSON.
+type mixInfo struct {
+	Game      string      `json:"game"`
+	Checksum  bool        `json:"checksum"`
+	Encrypted bool        `json:"encrypted"`
+	Size      uint32      `json:"size"`
+	Entries   []infoEntry `json:"entries"`
+}
+
+// infoEntry is an entry as listed by info.
+type infoEntry struct {
+	Index       string `json:"index"`
+	ID          uint32 `json:"id"`
+	Offset      uint32 `json:"offset"`
+	Size        uint32 `json:"size"`
+	Alias       string `json:"alias,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// listEntries lists the entries of a mix file and, if recursive, of the mix files nested in it.
+// Nested entries are prefixed by the index and the name of the mix file that contains them.
+// Entries that share body bytes are marked by their alias group number
+// and, if they have the same offset and size as an earlier entry, by the index of that entry.
+func listEntries(mixf *mix.Reader, index, path string, names *nameSources, recursive bool) []infoEntry {
+	var entries []infoEntry
+	aliases := mixf.Aliases()
+
+	for i, entry := range mixf.Entries {
+		idx := fmt.Sprintf("%s%04d", index, i)
+		name := entry.Name
+		if name != "" || path != "" {
+			name = path + entryFilename(entry)
+		}
+		alias := ""
+		if a := aliases[i]; a.Original != -1 {
+			alias = fmt.Sprintf("%d=%s%04d", a.Group, index, a.Original)
+		} else if a.Group != 0 {
+			alias = fmt.Sprintf("%d", a.Group)
+		}
+		entries = append(entries, infoEntry{
+			Index:       idx,
+			ID:          entry.ID,
+			Offset:      entry.Offset,
+			Size:        entry.Size,
+			Alias:       alias,
+			Type:        mixf.FileType(i),
+			Name:        name,
+			Description: entry.Description,
+			Source:      entry.Source,
+		})
+
+		if recursive {
+			if sub, err := mixf.OpenMix(i); err == nil {
+				resolveNames(sub, names)
+				sub.HarvestNames(mixf)
+				entries = append(entries, listEntries(sub, idx+"/", path+entryFilename(entry)+"/", names, true)...)
+			}
+		}
 	}
+	return entries
 }
 
 func commandRepair(args []string) error {
 	var (
 		cmd      = flag.NewFlagSet("repair", flag.ExitOnError)
 		filename = cmd.String("mix", "", "Path to .mix file.")
-		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2.")
+		outname  = cmd.String("out", "", "Path to a rebuilt .mix file. Repairs in place if omitted.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		dryRun   = cmd.Bool("dry-run", false, "List the problems without repairing.")
+		asJSON   = cmd.Bool("json", false, "List the problems as JSON with -dry-run.")
+		carve    = cmd.Bool("carve", false, "Rebuild the index from the contents of the body. Requires -out.")
+		raw      = cmd.Bool("raw", false, "The header is missing and the whole file is the body. Requires -carve and -game.")
+		partial  = cmd.String("incomplete", "", "How to repair entries that were cut short: drop or truncate.")
+		backup   = cmd.Bool("backup", false, "Keep the original in a .bak file when repairing in place.")
+		undo     = cmd.Bool("undo", false, "Restore the .bak file of an earlier repair.")
 	)
 
 	if err := cmd.Parse(args); err != nil {
 		return err
 	} else if len(*filename) == 0 {
 		return errors.New("no mix file specified")
+	} else if *carve && *outname == "" {
+		return errors.New("-carve requires -out")
+	} else if *raw && !*carve {
+		return errors.New("-raw requires -carve")
+	} else if *partial != "" && *partial != "drop" && *partial != "truncate" {
+		return fmt.Errorf("invalid incomplete mode: %s", *partial)
 	}
 
-	if gameID, err := stringToGameID(*game); err != nil {
-		return err
-	} else if f, err := os.OpenFile(*filename, os.O_RDWR, 0); err != nil {
+	if *undo {
+		return restoreBackup(*filename)
+	} else if *dryRun {
+		return checkMixFile(*filename, *game, *asJSON)
+	} else if *outname != "" {
+		return rebuildMixFile(*filename, *outname, *game, *carve, *raw, *partial)
+	}
+
+	if f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY); err != nil {
 		return err
 	} else if stat, err := f.Stat(); err != nil {
+		f.Close()
+		return err
+	} else {
+		defer f.Close()
+
+		if mixf.DeclaredCount != len(mixf.Entries) {
+			return errors.New("the index is truncated and cannot be repaired in place; use -out to rebuild it")
+		}
+
+		incompleteErr := checkIncomplete(mixf, *partial)
+		if *partial == "" && incompleteErr != nil {
+			return incompleteErr
+		}
+		salvageIncomplete(mixf, *partial)
+
+		mixf.RecoverLmd()
+
+		// salvaged entries change the body, so the mix file is rebuilt with a new body size and checksum
+		if incompleteErr != nil {
+			if err := replaceFile(f, *backup, mixf.Rebuild); err != nil {
+				return err
+			}
+			return incompleteErr
+		}
+
+		body := int64(mixf.BodyOffset)
+		if err := replaceFile(f, *backup, func(w io.Writer) error {
+			if err := mixf.RewriteHeader(w); err != nil {
+				return err
+			}
+			_, err := io.Copy(w, io.NewSectionReader(f, body, stat.Size()-body))
+			return err
+		}); err != nil {
+			return err
+		}
+
+		return incompleteErr
+	}
+}
+
+func commandCheck(args []string) error {
+	var (
+		cmd      = flag.NewFlagSet("check", flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		asJSON   = cmd.Bool("json", false, "List the problems as JSON.")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if len(*filename) == 0 {
+		return errors.New("no mix file specified")
+	}
+
+	return checkMixFile(*filename, *game, *asJSON)
+}
+
+func checkMixFile(filename, game string, asJSON bool) error {
+	f, mixf, err := openMixFile(filename, game, os.O_RDONLY)
+	if err != nil {
 		return err
-	} else if mix, err := unpackMixFile(io.NewSectionReader(f, 0, stat.Size()), gameID); err != nil {
+	}
+	defer f.Close()
+
+	problems, err := mixf.Check()
+	if err != nil {
 		return err
+	}
+
+	if asJSON {
+		if problems == nil {
+			problems = []mix.Problem{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(problems); err != nil {
+			return err
+		}
+	} else if len(problems) != 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+		fmt.Fprintf(tw, "offset\tentry\tproblem\tmessage\n")
+		for _, p := range problems {
+			entry := "-"
+			if p.Entry != -1 {
+				entry = fmt.Sprintf("%04d", p.Entry)
+			}
+			fmt.Fprintf(tw, "%08X\t%s\t%s\t%s\n", p.Offset, entry, p.Kind, p.Message)
+		}
+		tw.Flush()
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	} else if !asJSON {
+		fmt.Println("ok")
+	}
+	return nil
+}
+
+// rebuildMixFile writes a clean copy of a mix file to outname.
+// If carve is true then the index is rebuilt from the contents of the body,
+// and if raw is also true then the whole file is treated as the body.
+func rebuildMixFile(filename, outname, game string, carve, raw bool, partial string) error {
+	absfilename, _ := filepath.Abs(filename)
+	absoutname, _ := filepath.Abs(outname)
+	if absfilename == absoutname {
+		return errors.New("cannot rebuild a mix file in place")
+	}
+
+	var f *os.File
+	var mixf *mix.Reader
+	if raw {
+		gameID, err := stringToGameID(game)
+		if err != nil {
+			return err
+		} else if f, err = os.Open(filename); err != nil {
+			return err
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err != nil {
+			return err
+		} else {
+			mixf = mix.NewBodyReader(f, info.Size(), gameID)
+		}
 	} else {
+		var err error
+		if f, mixf, err = openMixFile(filename, game, os.O_RDONLY); err != nil {
+			return err
+		}
 		defer f.Close()
+	}
+
+	incompleteErr := checkIncomplete(mixf, partial)
+	if partial == "" && incompleteErr != nil {
+		return incompleteErr
+	}
+	salvageIncomplete(mixf, partial)
+
+	if carve {
+		mixf.Carve()
+		fmt.Fprintf(os.Stderr, "carved %d entries\n", len(mixf.Entries))
+	} else {
+		mixf.RecoverLmd()
+	}
+
+	if outfile, err := os.OpenFile(absoutname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		return err
+	} else {
+		defer outfile.Close()
+		wb := bufio.NewWriter(outfile)
+		if err := mixf.Rebuild(wb); err != nil {
+			return err
+		} else if err := wb.Flush(); err != nil {
+			return err
+		} else if err := outfile.Close(); err != nil {
+			return err
+		}
+		return incompleteErr
+	}
+}
+
+func commandConvert(args []string) error {
+	var (
+		cmd      = flag.NewFlagSet("convert", flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		outname  = cmd.String("out", "", "Path to output .mix file.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		checksum = cmd.Bool("checksum", false, "Compute checksum. Unchanged if omitted.")
+		encrypt  = cmd.Bool("encrypt", false, "Encrypt the index. Unchanged if omitted.")
+		keysrc   = cmd.String("keysource", "", "Hex encoded 80 byte key source. Original or random if omitted.")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if *filename == "" {
+		return errors.New("no mix file specified")
+	} else if *outname == "" {
+		return errors.New("no output file specified")
+	}
+
+	absfilename, _ := filepath.Abs(*filename)
+	absoutname, _ := filepath.Abs(*outname)
+	if absfilename == absoutname {
+		return errors.New("cannot convert a mix file in place")
+	}
+
+	keySource, err := parseKeySource(*keysrc)
+	if err != nil {
+		return err
+	}
+
+	f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	flags := mixf.Flags
+	cmd.Visit(func(fl *flag.Flag) {
+		switch {
+		case fl.Name == "checksum" && *checksum:
+			flags |= mix.FlagChecksum
+		case fl.Name == "checksum":
+			flags &^= mix.FlagChecksum
+		case fl.Name == "encrypt" && *encrypt:
+			flags |= mix.FlagEncrypted
+		case fl.Name == "encrypt":
+			flags &^= mix.FlagEncrypted
+		}
+	})
+
+	if outfile, err := os.OpenFile(absoutname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		return err
+	} else {
+		defer outfile.Close()
+		wb := bufio.NewWriter(outfile)
+		if err := mixf.Convert(wb, flags, keySource); err != nil {
+			return err
+		} else if err := wb.Flush(); err != nil {
+			return err
+		}
+		return outfile.Close()
+	}
+}
+
+func commandEdit(command string, args []string) error {
+	var (
+		cmd      = flag.NewFlagSet(command, flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		backup   = cmd.Bool("backup", false, "Keep the original in a .bak file.")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if *filename == "" {
+		return errors.New("no mix file specified")
+	} else if cmd.NArg() == 0 {
+		return errors.New("no files specified")
+	}
+
+	f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	mixf.RecoverLmd()
+	_ = mixf.ReadLmd()
+
+	fileID := mix.GetFileID(mixf.Game)
+
+	var files []mix.File
+	var remove []uint32
+
+	for _, arg := range cmd.Args() {
+		if command == "delete" {
+			if matches, err := mixf.Find(arg); err != nil {
+				return err
+			} else if len(matches) == 0 {
+				return fmt.Errorf("%s not found", arg)
+			} else {
+				for _, m := range matches {
+					remove = append(remove, mixf.Entries[m.Index].ID)
+				}
+			}
+		} else if fi, err := mix.StatFile(arg); err != nil {
+			return err
+		} else if exists := mixf.IndexByID(fileID(fi.Name())) != -1; command == "add" && exists {
+			return fmt.Errorf("%s already exists", fi.Name())
+		} else if command == "replace" && !exists {
+			return fmt.Errorf("%s not found", fi.Name())
+		} else {
+			files = append(files, fi)
+		}
+	}
+
+	return replaceFile(f, *backup, func(w io.Writer) error {
+		return mixf.Edit(w, files, remove)
+	})
+}
+
+// parseInterspersed parses flags that may follow positional arguments and returns the positional arguments.
+func parseInterspersed(cmd *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := cmd.Parse(args); err != nil {
+			return nil, err
+		} else if cmd.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, cmd.Arg(0))
+		args = cmd.Args()[1:]
+	}
+}
+
+func commandExtract(args []string) error {
+	var (
+		cmd      = flag.NewFlagSet("extract", flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		outname  = cmd.String("o", ".", "Output directory or - for stdout.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		names    = nameSourcesFlag(cmd)
+	)
+
+	patterns, err := parseInterspersed(cmd, args)
+	if err != nil {
+		return err
+	} else if *filename == "" {
+		return errors.New("no mix file specified")
+	} else if len(patterns) == 0 {
+		return errors.New("no files specified")
+	}
+
+	f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resolveNames(mixf, names)
+
+	var indices []int
+	var notFound []string
+	seen := map[int]bool{}
+	for _, pattern := range patterns {
+		if found, err := mixf.Find(pattern); err != nil {
+			return err
+		} else if len(found) == 0 {
+			notFound = append(notFound, pattern)
+		} else {
+			for _, m := range found {
+				if mixf.Entries[m.Index].Name == "" {
+					mixf.Entries[m.Index].Name = m.Name
+				}
+				if !seen[m.Index] {
+					seen[m.Index] = true
+					indices = append(indices, m.Index)
+				}
+			}
+		}
+	}
+
+	if *outname != "-" {
+		if err := os.MkdirAll(*outname, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range indices {
+		if *outname == "-" {
+			if _, err := io.Copy(os.Stdout, mixf.OpenFile(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fname := filepath.Join(*outname, typedFilename(mixf, i))
+		if outfile, err := os.OpenFile(fname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+			return err
+		} else if _, err := io.Copy(outfile, mixf.OpenFile(i)); err != nil {
+			outfile.Close()
+			return err
+		} else if err := outfile.Close(); err != nil {
+			return err
+		}
+	}
+
+	if len(notFound) != 0 {
+		return fmt.Errorf("not found: %s", strings.Join(notFound, ", "))
+	}
+	return nil
+}
+
+func commandLayout(args []string) error {
+	var (
+		cmd      = flag.NewFlagSet("layout", flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		names    = nameSourcesFlag(cmd)
+		asJSON   = cmd.Bool("json", false, "Print the layout as JSON.")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if len(*filename) == 0 {
+		return errors.New("no mix file specified")
+	}
+
+	f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resolveNames(mixf, names)
+
+	regions, err := mixf.Layout()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(regions)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+	fmt.Fprintf(tw, "offset\tend\tsize\tregion\tentry\tdescription\n")
+	for _, r := range regions {
+		entry := "-"
+		if r.Entry != -1 {
+			entry = fmt.Sprintf("%04d", r.Entry)
+		}
+		fmt.Fprintf(tw, "%08X\t%08X\t%d\t%s\t%s\t%s\n", r.Offset, r.Offset+r.Size, r.Size, r.Kind, entry, r.Description)
+	}
+	return tw.Flush()
+}
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// readWords reads the non-empty lines of a wordlist.
+func readWords(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// wordsHash returns the hex encoded SHA1 of a list of words, which identifies the words in the state file of crack.
+func wordsHash(words []string) string {
+	h := sha1.New()
+	for _, word := range words {
+		fmt.Fprintf(h, "%s\n", word)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readCrackState reads the state file of crack, which has a line per pattern and list of words
+// with the index of the next candidate, the number of candidates, the hash of the words and the pattern.
+// The state is keyed by the hash of the words and the pattern separated by a tab.
+func readCrackState(filename string) (map[string][2]uint64, error) {
+	state := map[string][2]uint64{}
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		next, err1 := strconv.ParseUint(fields[0], 10, 64)
+		total, err2 := strconv.ParseUint(fields[1], 10, 64)
+		if err1 == nil && err2 == nil {
+			state[fields[2]+"\t"+fields[3]] = [2]uint64{next, total}
+		}
+	}
+	return state, scanner.Err()
+}
+
+// writeCrackState writes the state file of crack with every entry of state,
+// so that the progress of earlier runs with other patterns or words is kept.
+func writeCrackState(filename string, state map[string][2]uint64) error {
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		s := state[key]
+		fmt.Fprintf(&b, "%d\t%d\t%s\n", s[0], s[1], key)
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+func commandCrack(args []string) error {
+	var (
+		cmd       = flag.NewFlagSet("crack", flag.ExitOnError)
+		filename  = cmd.String("mix", "", "Path to .mix file.")
+		game      = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+		names     = nameSourcesFlag(cmd)
+		outname   = cmd.String("out", "", "Path to csv file that names are appended to.")
+		statename = cmd.String("state", "", "Path to the file that the progress is saved to. Defaults to the -out path with .state appended.")
+		budget    = cmd.Duration("time", 0, "Time after which to stop, such as 10m or 2h. No limit if zero.")
+		wordfiles stringList
+		patterns  stringList
+	)
+	cmd.Var(&wordfiles, "words", "Path to wordlist with a word per line. Can be repeated.")
+	cmd.Var(&patterns, "pattern", "Pattern of names to try, such as {w}{0-99}.{ext}. Can be repeated. Defaults to {w}.")
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if len(*filename) == 0 {
+		return errors.New("no mix file specified")
+	} else if len(*outname) == 0 {
+		return errors.New("no output file specified")
+	} else if *statename == "" {
+		*statename = *outname + ".state"
+	}
+	if len(patterns) == 0 {
+		patterns = stringList{"{w}"}
+	}
+
+	var words []string
+	for _, wordfile := range wordfiles {
+		ws, err := readWords(wordfile)
+		if err != nil {
+			return err
+		}
+		words = append(words, ws...)
+	}
+
+	hash := wordsHash(words)
+
+	var ps []*mix.Pattern
+	for _, pattern := range patterns {
+		p, err := mix.ParsePattern(pattern, words)
+		if err != nil {
+			return err
+		}
+		ps = append(ps, p)
+	}
+
+	f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resolveNames(mixf, names)
+
+	found, err := mix.ReadGmd(*outname, mixf.Game)
+	if errors.Is(err, os.ErrNotExist) {
+		found = map[uint32]string{}
+	} else if err != nil {
+		return err
+	}
+
+	ids := map[uint32]bool{}
+	for _, entry := range mixf.Entries {
+		if _, ok := found[entry.ID]; !ok && entry.Name == "" && entry.ID != mix.LmdFileID(mixf.Game) {
+			ids[entry.ID] = true
+		}
+	}
+	fmt.Printf("%d unknown IDs\n", len(ids))
+
+	state, err := readCrackState(*statename)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(*outname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *budget)
+		defer cancel()
+	}
+
+	var writeErr error
+	fileID := mix.GetFileID(mixf.Game)
+	for i, p := range ps {
+		if len(ids) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		key := hash + "\t" + patterns[i]
+		start := uint64(0)
+		if s, ok := state[key]; ok && s[1] == p.Len() {
+			start = s[0]
+		}
+
+		next := mix.Crack(ctx, p, fileID, ids, start, func(h mix.Hit) {
+			if !ids[h.ID] {
+				return
+			}
+			delete(ids, h.ID)
+			fmt.Printf("%08X %s\n", h.ID, h.Name)
+			if _, err := fmt.Fprintf(out, "%s\t\n", h.Name); err != nil && writeErr == nil {
+				writeErr = err
+			}
+		})
+		state[key] = [2]uint64{next, p.Len()}
+		fmt.Printf("%s: tried %d of %d\n", patterns[i], next, p.Len())
+	}
+
+	if writeErr != nil {
+		return writeErr
+	} else if err := writeCrackState(*statename, state); err != nil {
+		return err
+	} else if ctx.Err() != nil {
+		fmt.Println("stopped; run again with the same arguments to resume")
+	}
+	return nil
+}
+
+func commandGmd(args []string) error {
+	var (
+		cmd     = flag.NewFlagSet("gmd", flag.ExitOnError)
+		outname = cmd.String("out", "", "Path to output database.")
+		game    = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Writes a csv of the game if given or else an XCC global mix database.dat.")
+		games   = []mix.Game{mix.GameCC1, mix.GameRA1, mix.GameCC2, mix.GameRA2}
+		inputs  = map[mix.Game]*string{}
+	)
+	for _, g := range games {
+		inputs[g] = cmd.String(g.String(), "", fmt.Sprintf("Path to mix database csv or XCC global mix database.dat to read the names of %s from. Embedded if omitted.", g))
+	}
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if len(*outname) == 0 {
+		return errors.New("no output file specified")
+	}
 
-		mix.RecoverLmd()
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
+	if *game != "" {
+		if g, err := stringToGameID(*game); err != nil {
 			return err
+		} else {
+			games = []mix.Game{g}
 		}
+	}
+
+	entries := map[mix.Game][]mix.GmdEntry{}
+	for _, g := range games {
+		es, err := mix.ReadGmdEntries(*inputs[g], g)
+		if err != nil {
+			return err
+		}
+		entries[g] = es
+	}
 
-		if err := mix.RewriteHeader(f); err != nil {
+	if outfile, err := os.OpenFile(*outname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		return err
+	} else {
+		defer outfile.Close()
+		if *game != "" {
+			err = mix.WriteGmd(outfile, entries[games[0]])
+		} else {
+			err = mix.WriteXccGmd(outfile, entries)
+		}
+		if err != nil {
 			return err
 		}
+		return outfile.Close()
+	}
+}
+
+func commandVerify(args []string) error {
+	var (
+		cmd      = flag.NewFlagSet("verify", flag.ExitOnError)
+		filename = cmd.String("mix", "", "Path to .mix file.")
+		game     = cmd.String("game", "", "One of cc1, cc2, ra1, ra2. Detected if omitted.")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	} else if len(*filename) == 0 {
+		return errors.New("no mix file specified")
+	}
+
+	if f, mixf, err := openMixFile(*filename, *game, os.O_RDONLY); err != nil {
+		return err
+	} else if v, err := mixf.Verify(); err != nil {
+		f.Close()
+		return err
+	} else {
+		defer f.Close()
+
+		fmt.Printf("truncated  %t\n", v.Truncated)
+		fmt.Printf("checksum   %t\n", v.Checksum)
+		if v.Checksum {
+			fmt.Printf("expected   %x\n", v.Expected)
+			fmt.Printf("actual     %x\n", v.Actual)
+		}
+		fmt.Printf("trailing   %d bytes\n", v.Trailing)
+
+		if v.Truncated {
+			return errors.New("body is truncated")
+		} else if v.Missing() {
+			return errors.New("checksum is missing")
+		} else if v.Mismatch() {
+			return errors.New("checksum mismatch")
+		} else if v.Trailing != 0 {
+			return errors.New("unexpected trailing data")
+		}
 
+		fmt.Println("ok")
 		return nil
 	}
 }
@@ -242,10 +1471,20 @@ func main() {
 	if len(os.Args) == 1 {
 		fmt.Println("usage: ccmixar <command> [<args>]")
 		fmt.Println("  command:")
-		fmt.Println("    info   Lists mix file contents.")
-		fmt.Println("    pack   Packs a directory in a mix file.")
-		fmt.Println("    repair Repairs a mangled mix file.")
-		fmt.Println("    unpack Unpacks a mix file to a directory.")
+		fmt.Println("    add     Adds files to a mix file.")
+		fmt.Println("    check   Lists the problems of a mix file.")
+		fmt.Println("    convert Changes the flags of a mix file.")
+		fmt.Println("    crack   Searches for the names of unknown IDs.")
+		fmt.Println("    delete  Deletes files from a mix file.")
+		fmt.Println("    extract Extracts files from a mix file.")
+		fmt.Println("    gmd     Converts global mix databases.")
+		fmt.Println("    info    Lists mix file contents.")
+		fmt.Println("    layout  Maps the bytes of a mix file.")
+		fmt.Println("    pack    Packs a directory in a mix file.")
+		fmt.Println("    repair  Repairs a mangled mix file.")
+		fmt.Println("    replace Replaces files in a mix file.")
+		fmt.Println("    unpack  Unpacks a mix file to a directory.")
+		fmt.Println("    verify  Verifies the checksum of a mix file.")
 		return
 	}
 
@@ -259,13 +1498,33 @@ func main() {
 		cmderr = commandUnpack(os.Args[2:])
 	case "repair":
 		cmderr = commandRepair(os.Args[2:])
+	case "add", "delete", "replace":
+		cmderr = commandEdit(os.Args[1], os.Args[2:])
+	case "extract":
+		cmderr = commandExtract(os.Args[2:])
+	case "check":
+		cmderr = commandCheck(os.Args[2:])
+	case "convert":
+		cmderr = commandConvert(os.Args[2:])
+	case "crack":
+		cmderr = commandCrack(os.Args[2:])
+	case "gmd":
+		cmderr = commandGmd(os.Args[2:])
+	case "layout":
+		cmderr = commandLayout(os.Args[2:])
+	case "verify":
+		cmderr = commandVerify(os.Args[2:])
 	default:
 		fmt.Printf("%q is not valid command.\n", os.Args[1])
 		os.Exit(2)
 	}
 
 	if cmderr != nil {
-		fmt.Println(cmderr.Error())
+		fmt.Fprintln(os.Stderr, cmderr.Error())
+		var exitErr *exitError
+		if errors.As(cmderr, &exitErr) {
+			os.Exit(exitErr.status)
+		}
 		os.Exit(1)
 	}
 }
@@ -1,9 +1,210 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/askeladdk/ccmixar/mix"
+)
 
 func TestCommandPack(t *testing.T) {
 	if err := commandPack([]string{"-dir", "./test/files", "-mix", "./test/mytest.mix", "-game", "ra2", "-database", "-checksum"}); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestCommandRepairUndo(t *testing.T) {
+	original, err := os.ReadFile("./test/ra1.mix")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "ra1.mix")
+	if err := os.WriteFile(filename, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := commandRepair([]string{"-mix", filename, "-game", "ra1", "-backup"}); err != nil {
+		t.Fatal(err)
+	} else if backup, err := os.ReadFile(filename + ".bak"); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(backup, original) {
+		t.Fatal("backup differs from the original")
+	}
+
+	if err := commandRepair([]string{"-mix", filename, "-undo"}); err != nil {
+		t.Fatal(err)
+	} else if restored, err := os.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(restored, original) {
+		t.Fatal("undo did not restore the original")
+	} else if _, err := os.Stat(filename + ".bak"); !os.IsNotExist(err) {
+		t.Fatal("backup was not consumed")
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.tmp")); len(matches) != 0 {
+		t.Fatalf("temporary files were left behind: %v", matches)
+	}
+}
+
+func TestCommandRepairIncomplete(t *testing.T) {
+	original, err := os.ReadFile("./test/ra1.mix")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{"drop", "truncate"} {
+		filename := filepath.Join(t.TempDir(), "ra1.mix")
+		if err := os.WriteFile(filename, original[:len(original)-30], 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := commandRepair([]string{"-mix", filename, "-game", "ra1", "-incomplete", mode}); err == nil {
+			t.Fatal("expected the exit status of salvaged entries")
+		} else if e, ok := err.(*exitError); !ok || (e.status != exitDropped && e.status != exitTruncated) {
+			t.Fatal(err)
+		}
+
+		f, mixf, err := openMixFile(filename, "ra1", os.O_RDONLY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := mixf.Verify()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if !v.OK() {
+			t.Fatalf("%s: %+v", mode, v)
+		}
+	}
+}
+
+func TestCrackState(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "hits.csv.state")
+	a, b := wordsHash([]string{"foo", "bar"}), wordsHash([]string{"baz"})
+	if a == b {
+		t.Fatal("different words have the same hash")
+	}
+
+	if err := writeCrackState(filename, map[string][2]uint64{a + "\t{w}": {5, 10}}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := readCrackState(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state[b+"\t{w}.shp"] = [2]uint64{1, 1}
+	if err := writeCrackState(filename, state); err != nil {
+		t.Fatal(err)
+	} else if state, err = readCrackState(filename); err != nil {
+		t.Fatal(err)
+	} else if len(state) != 2 || state[a+"\t{w}"] != [2]uint64{5, 10} || state[b+"\t{w}"] != [2]uint64{} {
+		t.Fatal(state)
+	}
+}
+
+// writeRawMix writes a cc1 mix file with the given index and body, which may be inconsistent.
+func writeRawMix(t *testing.T, entries []mix.Entry, bodySize uint32, body []byte) string {
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.LittleEndian, uint16(len(entries)))
+	_ = binary.Write(&b, binary.LittleEndian, bodySize)
+	for _, entry := range entries {
+		_ = binary.Write(&b, binary.LittleEndian, [3]uint32{entry.ID, entry.Offset, entry.Size})
+	}
+	b.Write(body)
+
+	filename := filepath.Join(t.TempDir(), "raw.mix")
+	if err := os.WriteFile(filename, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestCommandUnpackHardlink(t *testing.T) {
+	body := []byte("hello world")
+	size := uint32(len(body))
+
+	var nested bytes.Buffer
+	w := mix.NewWriter(&nested, mix.GameCC1)
+	if fw, err := w.CreateID(0x11111111); err != nil {
+		t.Fatal(err)
+	} else if _, err := fw.Write(body); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	nsize := uint32(nested.Len())
+
+	for _, c := range []struct {
+		name    string
+		entries []mix.Entry
+		size    uint32
+		body    []byte
+		args    []string
+		status  int
+		files   []string
+	}{
+		{
+			name:    "duplicate ID",
+			entries: []mix.Entry{{ID: 0x12345678, Size: size}, {ID: 0x12345678, Size: size}},
+			size:    size,
+			body:    body,
+			files:   []string{"12345678"},
+		},
+		{
+			name:    "alias",
+			entries: []mix.Entry{{ID: 0x12345678, Size: size}, {ID: 0x22345678, Size: size}},
+			size:    size,
+			body:    body,
+			files:   []string{"12345678", "22345678"},
+		},
+		{
+			name:    "nested mix",
+			entries: []mix.Entry{{ID: 0x12345678, Size: nsize}, {ID: 0x22345678, Size: nsize}},
+			size:    nsize,
+			body:    nested.Bytes(),
+			args:    []string{"-recursive"},
+			files:   []string{"12345678.mix/11111111", "22345678.mix/11111111"},
+		},
+		{
+			name:    "incomplete",
+			entries: []mix.Entry{{ID: 0x12345678, Size: size}, {ID: 0x22345678, Size: size}},
+			size:    size,
+			body:    body[:6],
+			args:    []string{"-incomplete", "drop"},
+			status:  exitDropped,
+		},
+	} {
+		filename := writeRawMix(t, c.entries, c.size, c.body)
+		dirname := filepath.Join(t.TempDir(), "out")
+		args := append([]string{"-game", "cc1", "-mix", filename, "-dir", dirname, "-duplicates", "hardlink"}, c.args...)
+
+		if err := commandUnpack(args); c.status == 0 && err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		} else if e, ok := err.(*exitError); c.status != 0 && (!ok || e.status != c.status) {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		var files []string
+		_ = filepath.Walk(dirname, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(dirname, path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if len(files) != len(c.files) {
+			t.Fatalf("%s: %v", c.name, files)
+		}
+		for k, f := range files {
+			if f != c.files[k] {
+				t.Fatalf("%s: %v", c.name, files)
+			} else if b, err := os.ReadFile(filepath.Join(dirname, f)); err != nil || !bytes.Equal(b, body) {
+				t.Fatalf("%s: %s has unexpected contents", c.name, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package mix
+
+import "sort"
+
+// Alias describes how an entry shares body bytes with other entries.
+type Alias struct {
+	// Group numbers the sets of entries whose contents overlap, starting at 1.
+	// It is 0 if the entry shares no bytes with other entries.
+	Group int
+	// Original is the index of the first entry with the same offset and size,
+	// or -1 if the entry is the first.
+	Original int
+}
+
+// Aliases groups the entries that share body bytes, either because they have
+// the same offset and size or because their ranges overlap.
+// Empty entries and entries that extend beyond the body are never aliased.
+func (mix *Reader) Aliases() []Alias {
+	aliases := make([]Alias, len(mix.Entries))
+	for i := range aliases {
+		aliases[i].Original = -1
+	}
+
+	type span struct {
+		offset, size uint32
+	}
+
+	var indices []int
+	originals := map[span]int{}
+	for i, entry := range mix.Entries {
+		if entry.Size == 0 || uint64(entry.Offset)+uint64(entry.Size) > uint64(mix.BodySize) {
+			continue
+		}
+		indices = append(indices, i)
+		s := span{entry.Offset, entry.Size}
+		if j, ok := originals[s]; ok {
+			aliases[i].Original = j
+		} else {
+			originals[s] = i
+		}
+	}
+
+	sort.SliceStable(indices, func(a, b int) bool {
+		return mix.Entries[indices[a]].Offset < mix.Entries[indices[b]].Offset
+	})
+
+	group := 0
+	for start := 0; start < len(indices); {
+		first := mix.Entries[indices[start]]
+		end := first.Offset + first.Size
+		stop := start + 1
+		for ; stop < len(indices); stop++ {
+			entry := mix.Entries[indices[stop]]
+			if entry.Offset >= end {
+				break
+			} else if entry.Offset+entry.Size > end {
+				end = entry.Offset + entry.Size
+			}
+		}
+		if stop-start > 1 {
+			group++
+			for _, i := range indices[start:stop] {
+				aliases[i].Group = group
+			}
+		}
+		start = stop
+	}
+
+	return aliases
+}
@@ -0,0 +1,36 @@
+package mix
+
+import "testing"
+
+func TestAliases(t *testing.T) {
+	mix := &Reader{
+		BodySize: 100,
+		Entries: []Entry{
+			{ID: 1, Offset: 0, Size: 10},
+			{ID: 2, Offset: 20, Size: 10},
+			{ID: 3, Offset: 0, Size: 10},
+			{ID: 4, Offset: 25, Size: 10},
+			{ID: 5, Offset: 40, Size: 10},
+			{ID: 6, Offset: 0, Size: 0},
+			{ID: 7, Offset: 95, Size: 10},
+			{ID: 8, Offset: 0, Size: 10},
+		},
+	}
+
+	expected := []Alias{
+		{Group: 1, Original: -1},
+		{Group: 2, Original: -1},
+		{Group: 1, Original: 0},
+		{Group: 2, Original: -1},
+		{Group: 0, Original: -1},
+		{Group: 0, Original: -1},
+		{Group: 0, Original: -1},
+		{Group: 1, Original: 0},
+	}
+
+	for i, alias := range mix.Aliases() {
+		if alias != expected[i] {
+			t.Errorf("entry %d: expected %+v but got %+v", i, expected[i], alias)
+		}
+	}
+}
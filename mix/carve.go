@@ -0,0 +1,225 @@
+package mix
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// NewBodyReader returns a Reader of a mix file whose header is missing.
+// All of ra is treated as the body and there are no entries until Carve is called.
+func NewBodyReader(ra io.ReaderAt, size int64, game Game) *Reader {
+	return &Reader{
+		BodySize:         uint32(size),
+		DeclaredBodySize: uint32(size),
+		Game:             game,
+		reader:           io.NewSectionReader(ra, 0, size),
+	}
+}
+
+// carved is a file found in the body by Carve.
+type carved struct {
+	offset, size int64
+	ext          string
+}
+
+// bodyScanner reads windows of the body through a large buffer.
+type bodyScanner struct {
+	mix  *Reader
+	buf  []byte
+	base int64
+	n    int
+}
+
+func (s *bodyScanner) window(p int64) []byte {
+	size := int64(s.mix.BodySize)
+	if p < s.base || p+sniffWindow > s.base+int64(s.n) && s.base+int64(s.n) < size {
+		s.base = p
+		s.n, _ = s.mix.reader.ReadAt(s.buf[:min64(int64(len(s.buf)), size-p)], int64(s.mix.BodyOffset)+p)
+	}
+	end := p - s.base + sniffWindow
+	if end > int64(s.n) {
+		end = int64(s.n)
+	}
+	return s.buf[p-s.base : end]
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// textLengthAt returns the length of the text that starts at offset p in the body.
+// The text ends early where another format that starts with text is recognized.
+func (s *bodyScanner) textLengthAt(p int64) int64 {
+	length := int64(0)
+	for p+length < int64(s.mix.BodySize) {
+		w := s.window(p + length)
+		n := textLength(w)
+		length += int64(n)
+		if n < len(w) {
+			break
+		}
+	}
+
+	for q := p + 1; q < p+length; q++ {
+		if ext, _, ok := sniff(s.window(q)); ok && ext != "ini" {
+			return q - p
+		}
+	}
+	return length
+}
+
+// carve finds the starts of recognizable files in the body.
+func (mix *Reader) carve() []carved {
+	s := &bodyScanner{
+		mix: mix,
+		buf: make([]byte, 0x400000),
+	}
+	ids := mix.entryIDs()
+	bodySize := int64(mix.BodySize)
+
+	var found []carved
+	for p := int64(0); p < bodySize; {
+		ext, size, ok := sniff(s.window(p))
+		if !ok {
+			p++
+			continue
+		}
+
+		if ext == "ini" {
+			size = s.textLengthAt(p)
+		} else if ext == "dat" {
+			if scan, ok := mix.scanLmd(uint32(p), ids); ok && len(scan.ends) > 0 {
+				size = int64(scan.size(uint32(size)))
+				if size == 0 {
+					size = int64(scan.ends[len(scan.ends)-1])
+				}
+			}
+		}
+		if p+size > bodySize {
+			size = bodySize - p
+		}
+
+		found = append(found, carved{offset: p, size: size, ext: ext})
+		if size > 0 {
+			p += size
+		} else {
+			p++
+		}
+	}
+
+	// files of unknown size extend to the next file,
+	// and bytes that belong to no file become files of their own
+	var files []carved
+	end := int64(0)
+	for i, c := range found {
+		if c.offset > end {
+			files = append(files, carved{offset: end, size: c.offset - end, ext: "bin"})
+		}
+		if c.size == 0 {
+			if i+1 < len(found) {
+				c.size = found[i+1].offset - c.offset
+			} else {
+				c.size = bodySize - c.offset
+			}
+		}
+		files = append(files, c)
+		end = c.offset + c.size
+	}
+	if end < bodySize {
+		files = append(files, carved{offset: end, size: bodySize - end, ext: "bin"})
+	}
+
+	return files
+}
+
+// Carve replaces the index with entries found by recognizing the starts of files in the body.
+// It is meant for mix files whose index is corrupt or missing.
+//
+// Recognized formats are the local mix database, PCX, ini, shp, aud, vqa, vxl and hva.
+// Files of unknown size extend to the next recognized file
+// and unrecognized bytes become entries of their own.
+//
+// Files are written in the order of their IDs, so the IDs named by the local mix database
+// and the IDs of the old index that are named by the global mix database
+// are assigned to the files in that order if their number and extensions agree.
+// Otherwise the entries are given generated names.
+func (mix *Reader) Carve() {
+	files := mix.carve()
+	fileID := GetFileID(mix.Game)
+	lmdID := LmdFileID(mix.Game)
+
+	names := map[uint32]string{}
+	for _, c := range files {
+		if c.ext != "dat" {
+			continue
+		}
+		r := io.NewSectionReader(mix.reader, int64(mix.BodyOffset)+c.offset, c.size)
+		if lmd, err := ReadLmd(r); err == nil {
+			for id, name := range lmd {
+				names[id] = name
+			}
+		}
+	}
+	if gmd, err := ReadGmd("", mix.Game); err == nil {
+		for _, entry := range mix.Entries {
+			if name, ok := gmd[entry.ID]; ok {
+				names[entry.ID] = name
+			}
+		}
+	}
+	delete(names, lmdID)
+
+	ids := make([]uint32, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return int32(ids[i]) < int32(ids[j])
+	})
+
+	var entries []Entry
+	var others []int
+	hasLmd := false
+	for _, c := range files {
+		entry := Entry{Offset: uint32(c.offset), Size: uint32(c.size)}
+		if c.ext == "dat" && !hasLmd {
+			entry.ID, entry.Name = lmdID, LmdFilename
+			hasLmd = true
+		} else {
+			others = append(others, len(entries))
+		}
+		entries = append(entries, entry)
+	}
+
+	assign := len(ids) == len(others)
+	if assign {
+		checked, mismatched := 0, 0
+		for k, i := range others {
+			ext := strings.TrimPrefix(strings.ToLower(path.Ext(names[ids[k]])), ".")
+			if c := files[i]; c.ext != "bin" && ext != "" {
+				checked++
+				if c.ext != ext {
+					mismatched++
+				}
+			}
+		}
+		assign = mismatched*4 <= checked
+	}
+
+	for k, i := range others {
+		if assign {
+			entries[i].ID, entries[i].Name = ids[k], names[ids[k]]
+		} else {
+			entries[i].Name = fmt.Sprintf("carved%04d.%s", k, files[i].ext)
+			entries[i].ID = fileID(entries[i].Name)
+		}
+	}
+
+	mix.Entries = entries
+}
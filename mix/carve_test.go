@@ -0,0 +1,62 @@
+package mix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCarve(t *testing.T) {
+	for _, game := range []Game{GameRA1, GameRA2} {
+		files, err := ListFilesToPack("../test/files", true, game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := packBuffer(t, game, 0, files...)
+
+		b := make([]byte, src.BodySize)
+		if _, err := src.reader.ReadAt(b, int64(src.BodyOffset)); err != nil {
+			t.Fatal(err)
+		}
+
+		// the header is missing
+		mix := NewBodyReader(bytes.NewReader(b), int64(len(b)), game)
+		mix.Carve()
+
+		if len(mix.Entries) != len(src.Entries) {
+			t.Fatalf("%s: expected %d entries but got %+v", game, len(src.Entries), mix.Entries)
+		}
+		for _, entry := range mix.Entries {
+			i := src.IndexByID(entry.ID)
+			if i == -1 {
+				t.Fatalf("%s: unexpected entry %+v", game, entry)
+			} else if src.Entries[i].Offset != entry.Offset || src.Entries[i].Size != entry.Size {
+				t.Fatalf("%s: expected %+v but got %+v", game, src.Entries[i], entry)
+			}
+		}
+	}
+}
+
+func TestCarveUnnamed(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", false, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := packBuffer(t, GameRA1, 0, files...)
+	for i := range src.Entries {
+		src.Entries[i] = Entry{ID: uint32(i), Offset: 0xFFFFFFFF}
+	}
+	src.Carve()
+
+	exts := map[string]bool{}
+	for i, entry := range src.Entries {
+		data, _ := io.ReadAll(src.OpenFile(i))
+		if f, err := src.OpenFile(i).ReadAt(make([]byte, 1), 0); f != 1 || err != nil || len(data) == 0 {
+			t.Fatalf("entry %+v is empty", entry)
+		}
+		exts[entry.Name[len(entry.Name)-3:]] = true
+	}
+	if len(src.Entries) != 3 || !exts["shp"] || !exts["pcx"] || !exts["ini"] {
+		t.Fatalf("%+v", src.Entries)
+	}
+}
@@ -0,0 +1,133 @@
+package mix
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of problems reported by Check.
+const (
+	ProblemBeyondBody   = "beyond-body"
+	ProblemIncomplete   = "incomplete"
+	ProblemOverlap      = "overlap"
+	ProblemDuplicateID  = "duplicate-id"
+	ProblemUnsorted     = "unsorted"
+	ProblemBodySize     = "body-size"
+	ProblemCount        = "entry-count"
+	ProblemLmdRelocated = "lmd-relocated"
+	ProblemTruncated    = "truncated"
+	ProblemChecksum     = "checksum"
+	ProblemTrailing     = "trailing-data"
+)
+
+// Problem is a problem found by Check.
+type Problem struct {
+	// Kind is one of the Problem constants.
+	Kind string `json:"kind"`
+	// Entry is the index of the entry concerned or -1.
+	Entry int `json:"entry"`
+	// Offset is the offset from the start of the file where the problem is located.
+	Offset int64 `json:"offset"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// indexOffset returns the offset of the i-th index entry from the start of the file.
+// The offset of an encrypted index entry is its offset as if the index were decrypted.
+func (mix *Reader) indexOffset(i int) int64 {
+	if mix.flagless {
+		return 6 + 12*int64(i)
+	} else if (mix.Flags & FlagEncrypted) != 0 {
+		return 84 + 6 + 12*int64(i)
+	}
+	return 10 + 12*int64(i)
+}
+
+// Check lists the problems of the mix file without changing it.
+// It must be called before the index is changed by RecoverLmd.
+func (mix *Reader) Check() ([]Problem, error) {
+	var problems []Problem
+
+	report := func(kind string, entry int, offset int64, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Kind:    kind,
+			Entry:   entry,
+			Offset:  offset,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	body := int64(mix.BodyOffset)
+	lmdID := LmdFileID(mix.Game)
+	seen := map[uint32]int{}
+	var inBody []int
+
+	for i, entry := range mix.Entries {
+		if j, ok := seen[entry.ID]; ok {
+			report(ProblemDuplicateID, i, mix.indexOffset(i), "entry %d has the same ID %08X as entry %d", i, entry.ID, j)
+		} else {
+			seen[entry.ID] = i
+		}
+
+		if entry.ID == lmdID && !mix.lmdIntact(i) {
+			if offset, size, found := mix.recoverLmdIndex(); found && (offset != entry.Offset || size != entry.Size) {
+				report(ProblemLmdRelocated, i, body+int64(offset), "local mix database of %d bytes was found at body offset %d", size, offset)
+			}
+		}
+
+		if i > 0 && int32(mix.Entries[i-1].ID) > int32(entry.ID) {
+			report(ProblemUnsorted, i, mix.indexOffset(i), "ID %08X of entry %d is less than ID %08X of entry %d", entry.ID, i, mix.Entries[i-1].ID, i-1)
+		}
+
+		if end := uint64(entry.Offset) + uint64(entry.Size); mix.incomplete(entry) {
+			report(ProblemIncomplete, i, body+int64(entry.Offset), "entry %d (%08X) is cut short: %d of %d bytes are present", i, entry.ID, mix.Available(i), entry.Size)
+		} else if end > uint64(mix.BodySize) {
+			report(ProblemBeyondBody, i, body+int64(entry.Offset), "entry %d (%08X) ends at %d which is beyond the body of %d bytes", i, entry.ID, end, mix.BodySize)
+		} else if entry.Size != 0 {
+			inBody = append(inBody, i)
+		}
+	}
+
+	sort.SliceStable(inBody, func(a, b int) bool {
+		return mix.Entries[inBody[a]].Offset < mix.Entries[inBody[b]].Offset
+	})
+
+	end, last := uint32(0), -1
+	for _, i := range inBody {
+		entry := mix.Entries[i]
+		if last != -1 && entry.Offset < end {
+			report(ProblemOverlap, i, body+int64(entry.Offset), "entry %d (%08X) overlaps entry %d (%08X)", i, entry.ID, last, mix.Entries[last].ID)
+		}
+		if entry.Offset+entry.Size > end {
+			end, last = entry.Offset+entry.Size, i
+		}
+	}
+
+	if mix.DeclaredBodySize != mix.BodySize {
+		report(ProblemBodySize, -1, mix.indexOffset(0)-4, "header declares a body of %d bytes but the file has %d bytes after the header", mix.DeclaredBodySize, mix.BodySize)
+	}
+
+	if mix.DeclaredCount != len(mix.Entries) {
+		report(ProblemCount, -1, mix.indexOffset(0)-6, "header declares %d entries but the index ends after %d entries", mix.DeclaredCount, len(mix.Entries))
+	}
+
+	v, err := mix.Verify()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case v.Truncated:
+		report(ProblemTruncated, -1, mix.reader.Size(), "file ends %d bytes before the end of the declared body", body+int64(mix.DeclaredBodySize)-mix.reader.Size())
+	case v.Missing():
+		report(ProblemChecksum, -1, body+int64(mix.DeclaredBodySize), "checksum is missing")
+	case v.Mismatch():
+		report(ProblemChecksum, -1, body+int64(mix.DeclaredBodySize), "checksum %x does not match the body checksum %x", v.Expected, v.Actual)
+	}
+
+	if v.Trailing > 0 {
+		report(ProblemTrailing, -1, mix.reader.Size()-v.Trailing, "%d bytes follow the body", v.Trailing)
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,60 @@
+package mix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameRA2, FlagChecksum, files...)
+
+	mix, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if problems, err := mix.Check(); err != nil {
+		t.Fatal(err)
+	} else if len(problems) != 0 {
+		t.Fatalf("%+v", problems)
+	}
+
+	b[len(b)-21] ^= 0xFF
+	b = append(b, 0, 0, 0, 0)
+	mix, err = NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lmd := mix.IndexByID(LmdFileID(GameRA2))
+	mix.Entries[lmd].Offset += 0x1000000
+	mix.Entries[0], mix.Entries[1] = mix.Entries[1], mix.Entries[0]
+	mix.Entries = append(mix.Entries, Entry{ID: mix.Entries[0].ID, Offset: 8, Size: 16})
+
+	problems, err := mix.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]int{}
+	for _, p := range problems {
+		kinds[p.Kind]++
+	}
+
+	for _, kind := range []string{
+		ProblemBeyondBody,
+		ProblemLmdRelocated,
+		ProblemUnsorted,
+		ProblemDuplicateID,
+		ProblemOverlap,
+		ProblemBodySize,
+		ProblemChecksum,
+		ProblemTrailing,
+	} {
+		if kinds[kind] == 0 {
+			t.Errorf("%s not reported: %+v", kind, problems)
+		}
+	}
+}
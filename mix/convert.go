@@ -0,0 +1,53 @@
+package mix
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"errors"
+	"io"
+)
+
+// Convert writes the mix file to w with different flags.
+// The index and the body are copied unchanged, the header is encrypted or decrypted
+// and the checksum is recomputed or dropped as needed.
+// The index is encrypted with keySource if it is not nil, with the original key source
+// if the mix file was encrypted and with a random key source otherwise.
+// A mix file without flags gets a flags word if flags is not zero, unless its game is cc1.
+func (mix *Reader) Convert(w io.Writer, flags uint32, keySource []byte) error {
+	if keySource == nil && (flags&FlagEncrypted) != 0 {
+		if mix.KeySource != nil {
+			keySource = mix.KeySource
+		} else if ks, err := NewKeySource(rand.Reader); err != nil {
+			return err
+		} else {
+			keySource = ks
+		}
+	}
+
+	game := mix.headerGame()
+	if game == GameCC1 && flags != 0 {
+		if mix.Game == GameCC1 {
+			return errors.New("the header of cc1 mix files cannot carry flags")
+		}
+		game = mix.Game
+	}
+
+	if err := writeHeader(w, game, flags, keySource, mix.Entries, mix.BodySize); err != nil {
+		return err
+	}
+
+	body := io.NewSectionReader(mix.reader, int64(mix.BodyOffset), int64(mix.BodySize))
+
+	if (flags & FlagChecksum) != 0 {
+		h := sha1.New()
+		if _, err := io.Copy(io.MultiWriter(w, h), body); err != nil {
+			return err
+		} else if _, err := w.Write(h.Sum(nil)); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	_, err := io.Copy(w, body)
+	return err
+}
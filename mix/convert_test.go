@@ -0,0 +1,109 @@
+package mix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := packBuffer(t, GameRA1, FlagChecksum|FlagEncrypted, files...)
+
+	for _, flags := range []uint32{0, FlagChecksum, FlagEncrypted, FlagChecksum | FlagEncrypted} {
+		var b bytes.Buffer
+		if err := src.Convert(&b, flags, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		dst, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), GameRA1)
+		if err != nil {
+			t.Fatal(err)
+		} else if dst.Flags != flags {
+			t.Fatalf("expected flags %x but got %x", flags, dst.Flags)
+		} else if (flags&FlagEncrypted) != 0 && !bytes.Equal(dst.KeySource, src.KeySource) {
+			t.Fatal("key source was not kept")
+		} else if v, err := dst.Verify(); err != nil {
+			t.Fatal(err)
+		} else if !v.OK() {
+			t.Fatalf("%x: %+v", flags, v)
+		} else if len(dst.Entries) != len(src.Entries) {
+			t.Fatal(len(dst.Entries))
+		}
+
+		for i := range src.Entries {
+			expected, _ := io.ReadAll(src.OpenFile(i))
+			actual, _ := io.ReadAll(dst.OpenFile(i))
+			if src.Entries[i] != dst.Entries[i] || !bytes.Equal(expected, actual) {
+				t.Fatalf("%x: entry %d differs", flags, i)
+			}
+		}
+	}
+}
+
+func TestConvertFlagsCC1(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameCC1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameRA1, FlagChecksum, files...)
+	src, err := NewReader(bytes.NewReader(b), int64(len(b)), GameCC1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := src.Convert(&out, FlagChecksum, nil); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(out.Bytes(), b) {
+		t.Fatal("flags were not kept")
+	}
+}
+
+func TestConvertFlagless(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameCC1, 0, files...)
+
+	if src, err := NewReader(bytes.NewReader(b), int64(len(b)), GameCC1); err != nil {
+		t.Fatal(err)
+	} else if err := src.Convert(io.Discard, FlagChecksum, nil); err == nil {
+		t.Fatal("expected cc1 to refuse flags")
+	}
+
+	src, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, flags := range []uint32{0, FlagChecksum | FlagEncrypted} {
+		var out bytes.Buffer
+		if err := src.Convert(&out, flags, nil); err != nil {
+			t.Fatal(err)
+		} else if flags == 0 && !bytes.Equal(out.Bytes(), b) {
+			t.Fatal("header format was changed")
+		}
+
+		dst, err := NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()), GameRA1)
+		if err != nil {
+			t.Fatal(err)
+		} else if dst.Flags != flags {
+			t.Fatalf("expected flags %x but got %x", flags, dst.Flags)
+		} else if v, err := dst.Verify(); err != nil {
+			t.Fatal(err)
+		} else if !v.OK() {
+			t.Fatalf("%x: %+v", flags, v)
+		}
+		for i := range src.Entries {
+			expected, _ := io.ReadAll(src.OpenFile(i))
+			actual, _ := io.ReadAll(dst.OpenFile(i))
+			if src.Entries[i] != dst.Entries[i] || !bytes.Equal(expected, actual) {
+				t.Fatalf("%x: entry %d differs", flags, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package mix
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Extensions are the file extensions that {ext} expands to in a pattern.
+var Extensions = []string{
+	"aud", "bik", "bin", "cps", "csf", "dat", "des", "fnt", "hva", "ini",
+	"lun", "map", "mix", "mpr", "pal", "pcx", "shp", "sno", "tem", "tmp",
+	"txt", "ubn", "urb", "vqa", "vqp", "vxl", "wav", "wsa",
+}
+
+// Pattern generates candidate file names from a template.
+//
+// Text in braces is replaced by each of a set of strings in turn:
+// {w} by each word, {ext} by each of Extensions, {a|b|c} by a, b and c,
+// and {0-99} by the numbers 0 to 99. {00-99} pads the numbers with zeros to the width of the first.
+// All other text is copied as is. The pattern ship{0-9}.{shp|vxl}
+// generates ship0.shp, ship0.vxl, ship1.shp and so on.
+type Pattern struct {
+	parts [][]string
+	total uint64
+}
+
+// ParsePattern parses a pattern template. words are the strings that {w} is replaced by.
+func ParsePattern(pattern string, words []string) (*Pattern, error) {
+	p := &Pattern{total: 1}
+	s := pattern
+
+	for len(s) > 0 {
+		var part []string
+		if i := strings.IndexByte(s, '{'); i == -1 {
+			part, s = []string{s}, ""
+		} else if i > 0 {
+			part, s = []string{s[:i]}, s[i:]
+		} else if j := strings.IndexByte(s, '}'); j == -1 {
+			return nil, fmt.Errorf("pattern %q: unclosed brace", pattern)
+		} else if part = expandGroup(s[1:j], words); len(part) == 0 {
+			return nil, fmt.Errorf("pattern %q: {%s} is empty", pattern, s[1:j])
+		} else {
+			s = s[j+1:]
+		}
+
+		if p.total > ^uint64(0)/uint64(len(part)) {
+			return nil, fmt.Errorf("pattern %q: too many candidates", pattern)
+		}
+		p.total *= uint64(len(part))
+		p.parts = append(p.parts, part)
+	}
+
+	return p, nil
+}
+
+func expandGroup(group string, words []string) []string {
+	if group == "w" {
+		return words
+	} else if group == "ext" {
+		return Extensions
+	} else if from, to, ok := parseRange(group); ok {
+		width := 0
+		if lo := group[:strings.IndexByte(group, '-')]; len(lo) > 1 && lo[0] == '0' {
+			width = len(lo)
+		}
+		var xs []string
+		for n := from; n <= to; n++ {
+			xs = append(xs, fmt.Sprintf("%0*d", width, n))
+		}
+		return xs
+	}
+	return strings.Split(group, "|")
+}
+
+func parseRange(group string) (int, int, bool) {
+	i := strings.IndexByte(group, '-')
+	if i == -1 {
+		return 0, 0, false
+	}
+	from, err1 := strconv.Atoi(group[:i])
+	to, err2 := strconv.Atoi(group[i+1:])
+	if err1 != nil || err2 != nil || from < 0 || to < from {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// Len returns the number of candidates.
+func (p *Pattern) Len() uint64 {
+	return p.total
+}
+
+// Candidate appends the k-th candidate to buf.
+// The last part of the pattern varies fastest.
+func (p *Pattern) Candidate(k uint64, buf []byte) []byte {
+	for i, j := range p.indices(k) {
+		buf = append(buf, p.parts[i][j]...)
+	}
+	return buf
+}
+
+// indices returns the index in each part of the k-th candidate.
+func (p *Pattern) indices(k uint64) []int {
+	indices := make([]int, len(p.parts))
+	for i := len(p.parts) - 1; i >= 0; i-- {
+		n := uint64(len(p.parts[i]))
+		indices[i] = int(k % n)
+		k /= n
+	}
+	return indices
+}
+
+// each calls f with the candidates from the from-th up to the to-th.
+func (p *Pattern) each(from, to uint64, f func(name []byte)) {
+	indices := p.indices(from)
+	var buf []byte
+	for k := from; k < to; k++ {
+		buf = buf[:0]
+		for i, part := range p.parts {
+			buf = append(buf, part[indices[i]]...)
+		}
+		f(buf)
+
+		for i := len(indices) - 1; i >= 0; i-- {
+			if indices[i]++; indices[i] < len(p.parts[i]) {
+				break
+			}
+			indices[i] = 0
+		}
+	}
+}
+
+// Hit is a candidate name whose ID is one of the IDs that are searched for.
+type Hit struct {
+	ID   uint32
+	Name string
+}
+
+// Crack hashes the candidates of p from start onwards on all CPUs and reports the names
+// whose IDs are in ids to hit, in the order of the candidates.
+// It stops when all candidates are tried or ctx is done and returns the index of the first candidate
+// that was not tried, from where a later call can resume.
+func Crack(ctx context.Context, p *Pattern, fileID FileID, ids map[uint32]bool, start uint64, hit func(Hit)) uint64 {
+	const chunkSize = 1 << 16
+
+	workers := runtime.NumCPU()
+	next := start
+
+	for next < p.total && ctx.Err() == nil {
+		results := make([][]Hit, workers)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			from := next + uint64(w)*chunkSize
+			if from >= p.total {
+				break
+			}
+			to := from + chunkSize
+			if to > p.total {
+				to = p.total
+			}
+
+			wg.Add(1)
+			go func(w int, from, to uint64) {
+				defer wg.Done()
+				p.each(from, to, func(name []byte) {
+					if id := fileID(string(name)); ids[id] {
+						results[w] = append(results[w], Hit{ID: id, Name: string(name)})
+					}
+				})
+			}(w, from, to)
+		}
+		wg.Wait()
+
+		for _, hits := range results {
+			for _, h := range hits {
+				hit(h)
+			}
+		}
+
+		next += uint64(workers) * chunkSize
+		if next > p.total {
+			next = p.total
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,73 @@
+package mix
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	p, err := ParsePattern("{w}{08-10}.{shp|vxl}", []string{"tank", "jeep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"tank08.shp", "tank08.vxl", "tank09.shp", "tank09.vxl", "tank10.shp", "tank10.vxl",
+		"jeep08.shp", "jeep08.vxl", "jeep09.shp", "jeep09.vxl", "jeep10.shp", "jeep10.vxl",
+	}
+	if p.Len() != uint64(len(expected)) {
+		t.Fatalf("expected %d candidates but got %d", len(expected), p.Len())
+	}
+	for k, name := range expected {
+		if c := string(p.Candidate(uint64(k), nil)); c != name {
+			t.Fatalf("candidate %d: expected %s but got %s", k, name, c)
+		}
+	}
+
+	for _, s := range []string{"{w", "{w}.shp", "{a|b}{ext"} {
+		if _, err := ParsePattern(s, nil); err == nil {
+			t.Fatalf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestCrack(t *testing.T) {
+	words := []string{"1tnk", "2tnk", "3tnk", "4tnk", "5tnk"}
+	p, err := ParsePattern("{w}{|-}.{ext}", words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[uint32]bool{
+		FileIDV1("5TNK.SHP"):  true,
+		FileIDV1("2tnk-.vxl"): true,
+		0xDEADBEEF:            true,
+	}
+
+	var hits []Hit
+	next := Crack(context.Background(), p, FileIDV1, ids, 0, func(h Hit) {
+		hits = append(hits, h)
+	})
+	if next != p.Len() {
+		t.Fatalf("expected to stop at %d but stopped at %d", p.Len(), next)
+	} else if len(hits) != 2 || hits[0].Name != "2tnk-.vxl" || hits[1].Name != "5tnk.shp" {
+		t.Fatalf("%+v", hits)
+	}
+
+	// resume after the first hit
+	hits = nil
+	start := p.Len() - uint64(len(Extensions))*2
+	Crack(context.Background(), p, FileIDV1, ids, start, func(h Hit) {
+		hits = append(hits, h)
+	})
+	if len(hits) != 1 || hits[0].ID != FileIDV1("5tnk.shp") {
+		t.Fatalf("%+v", hits)
+	}
+
+	// a cancelled context stops before trying anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if next := Crack(ctx, p, FileIDV1, ids, 3, func(Hit) {}); next != 3 {
+		t.Fatalf("expected to stop at 3 but stopped at %d", next)
+	}
+}
@@ -1,7 +1,9 @@
-package main
+package mix
 
 import (
+	"bytes"
 	"crypto/rsa"
+	"io"
 	"math/big"
 )
 
@@ -64,3 +66,36 @@ func blowfishKeyFromKeySource(keySource []byte) []byte {
 	byteswap(key)
 	return key
 }
+
+// keySourceFromBlowfishKey is the inverse of blowfishKeyFromKeySource.
+// The key must be 56 bytes long.
+func keySourceFromBlowfishKey(key []byte) []byte {
+	k := make([]byte, len(key))
+	copy(k, key)
+	byteswap(k)
+	d := new(big.Int).SetBytes(k)
+	a := new(big.Int).Rsh(d, 312)
+	b := new(big.Int).Sub(d, new(big.Int).Lsh(a, 312))
+	s0 := rsatransform(a.Bytes(), privateKey.D, publicKey.N)
+	s1 := rsatransform(b.Bytes(), privateKey.D, publicKey.N)
+	ks := [80]byte{}
+	copy(ks[40-len(s0):40], s0)
+	copy(ks[80-len(s1):], s1)
+	byteswap(ks[:])
+	return ks[:]
+}
+
+// NewKeySource generates a key source for a random blowfish key using the random number generator rand.
+func NewKeySource(rand io.Reader) ([]byte, error) {
+	key := make([]byte, 56)
+	for {
+		if _, err := io.ReadFull(rand, key); err != nil {
+			return nil, err
+		} else if key[len(key)-1] == 0 {
+			// the key would be shortened by blowfishKeyFromKeySource
+			continue
+		} else if ks := keySourceFromBlowfishKey(key); bytes.Equal(blowfishKeyFromKeySource(ks), key) {
+			return ks, nil
+		}
+	}
+}
@@ -1,7 +1,8 @@
-package main
+package mix
 
 import (
 	"bytes"
+	"crypto/rand"
 	"testing"
 )
 
@@ -41,3 +42,36 @@ func TestBlowfishKeyFromSource(t *testing.T) {
 		t.Fatal("unexpected blowfish key")
 	}
 }
+
+func TestKeySourceFromBlowfishKey(t *testing.T) {
+	expectedBlowfishKey := []byte{
+		0x53, 0xb9, 0xb7, 0x6c, 0xec, 0x6c, 0x03, 0xb8,
+		0x38, 0xb8, 0x6d, 0x11, 0x08, 0xac, 0x4a, 0x91,
+		0x9d, 0x2f, 0x0c, 0x0c, 0x0c, 0x0c, 0x0c, 0x0c,
+		0x0c, 0x0c, 0x0c, 0x0c, 0x0c, 0x0c, 0x0c, 0x0c,
+		0x71, 0x6e, 0x94, 0xac, 0x2c, 0xac, 0xf0, 0x08,
+		0x88, 0x08, 0xb5, 0x52, 0x4f, 0xec, 0x97, 0xd2,
+		0x2a, 0x48, 0x05, 0x05, 0x05, 0x05, 0x05, 0x05,
+	}
+
+	keySource := keySourceFromBlowfishKey(expectedBlowfishKey)
+	if bfkey := blowfishKeyFromKeySource(keySource); !bytes.Equal(bfkey, expectedBlowfishKey) {
+		t.Fatal("unexpected blowfish key")
+	}
+}
+
+func TestNewKeySource(t *testing.T) {
+	a, err := NewKeySource(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKeySource(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 80 || bytes.Equal(a, b) {
+		t.Fatal("key sources are not random")
+	} else if len(blowfishKeyFromKeySource(a)) != 56 {
+		t.Fatal("unexpected blowfish key length")
+	}
+}
@@ -0,0 +1,107 @@
+package mix
+
+// Detection is the result of DetectGame.
+type Detection struct {
+	// Game is the most likely game.
+	Game Game
+	// Confidence ranges from 0 (a guess) to 1 (certain).
+	Confidence float64
+	// Lmd is true if the game was read from the local mix database.
+	Lmd bool
+	// Matches counts the entries whose IDs are in the global mix database of each candidate game.
+	Matches map[Game]int
+}
+
+// DetectGame guesses the game of the mix file from the shape of its header,
+// the local mix database and the number of IDs found in the global mix database of each game.
+// The Game of the Reader is not changed.
+func (mix *Reader) DetectGame() Detection {
+	var candidates []Game
+	if mix.flagless {
+		candidates = []Game{GameCC1, GameRA1}
+	} else {
+		candidates = []Game{GameRA1, GameCC2, GameRA2}
+	}
+
+	if game, found, ok := mix.detectLmdGame(); ok {
+		for _, g := range candidates {
+			if g == game {
+				return Detection{
+					Game:       game,
+					Confidence: 1,
+					Lmd:        true,
+				}
+			}
+		}
+	} else if found {
+		candidates = filterGames(candidates, func(g Game) bool {
+			return mix.IndexByID(LmdFileID(g)) != -1
+		})
+	}
+
+	det := Detection{
+		Game:    candidates[0],
+		Matches: map[Game]int{},
+	}
+
+	best, second := -1, 0
+	for _, g := range candidates {
+		mapper, err := ReadGmd("", g)
+		if err != nil {
+			continue
+		}
+		n := 0
+		for _, entry := range mix.Entries {
+			if _, ok := mapper[entry.ID]; ok {
+				n++
+			}
+		}
+		det.Matches[g] = n
+		if n > best {
+			det.Game, best, second = g, n, best
+		} else if n > second {
+			second = n
+		}
+	}
+
+	if best > 0 {
+		if second < 0 {
+			second = 0
+		}
+		det.Confidence = float64(best-second) / float64(len(mix.Entries))
+	}
+
+	return det
+}
+
+// detectLmdGame reads the game from the local mix database.
+// found is true if there is an entry with the ID of a local mix database.
+func (mix *Reader) detectLmdGame() (game Game, found, ok bool) {
+	for _, g := range []Game{GameRA1, GameRA2} {
+		if i := mix.IndexByID(LmdFileID(g)); i == -1 {
+			continue
+		} else if entry := mix.Entries[i]; uint64(entry.Offset)+uint64(entry.Size) > uint64(mix.BodySize) {
+			found = true
+		} else if game, err := readLmdHeader(mix.OpenFile(i)); err != nil {
+			found = true
+		} else if GetFileID(game)(LmdFilename) == entry.ID {
+			return game, true, true
+		} else {
+			found = true
+		}
+	}
+	return 0, found, false
+}
+
+func filterGames(games []Game, keep func(Game) bool) []Game {
+	var kept []Game
+	for _, g := range games {
+		if keep(g) {
+			kept = append(kept, g)
+		}
+	}
+	if len(kept) == 0 {
+		return games
+	}
+	return kept
+}
@@ -0,0 +1,43 @@
+package mix
+
+import "testing"
+
+func TestDetectGame(t *testing.T) {
+	for _, game := range []Game{GameCC1, GameRA1, GameCC2, GameRA2} {
+		files, err := ListFilesToPack("../test/files", true, game)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		flags := uint32(0)
+		if game != GameCC1 {
+			flags = FlagEncrypted
+		}
+
+		mix := packBuffer(t, game, flags, files...)
+		if det := mix.DetectGame(); det.Game != game || !det.Lmd || det.Confidence != 1 {
+			t.Fatalf("%s: %+v", game, det)
+		}
+	}
+}
+
+func TestDetectGameGmd(t *testing.T) {
+	files, err := ReadDir("../test/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mix := packBuffer(t, GameCC1, 0, files...)
+	if det := mix.DetectGame(); det.Lmd {
+		t.Fatalf("%+v", det)
+	} else if det.Game != GameCC1 && det.Game != GameRA1 {
+		t.Fatalf("%+v", det)
+	}
+
+	mix = packBuffer(t, GameRA2, FlagChecksum, files...)
+	if det := mix.DetectGame(); det.Lmd {
+		t.Fatalf("%+v", det)
+	} else if _, ok := det.Matches[GameCC1]; ok {
+		t.Fatalf("%+v", det)
+	}
+}
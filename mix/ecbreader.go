@@ -1,4 +1,4 @@
-package main
+package mix
 
 import (
 	"bytes"
@@ -22,9 +22,9 @@ func newECBReader(r io.Reader, b cipher.Block) *ecbReader {
 func (r *ecbReader) Read(p []byte) (int, error) {
 	if r.buffer.Len() < len(p) {
 		blksz := r.block.BlockSize()
-		n := (len(p) + blksz - 1) & ^(blksz - 1)
+		n := (len(p) - r.buffer.Len() + blksz - 1) & ^(blksz - 1)
 		t := make([]byte, n)
-		if _, err := r.reader.Read(t); err != nil {
+		if _, err := io.ReadFull(r.reader, t); err != nil {
 			return 0, err
 		}
 		r.block.Decrypt(t, t)
@@ -0,0 +1,95 @@
+package mix
+
+import "io"
+
+// Edit writes a copy of the mix file to dst with the entries whose IDs are in remove deleted
+// and with files added, replacing the entries that have the same IDs.
+// The header format, the flags and the key source are kept and untouched entries are copied unchanged.
+// If the mix file has a local mix database then it is rewritten with the names of the new entry set.
+// If it has a descriptions entry then it is rewritten with the descriptions of the remaining entries,
+// and replaced files keep the descriptions of the entries that they replace.
+func (mix *Reader) Edit(dst io.Writer, files []File, remove []uint32) error {
+	w := mix.newWriter(dst)
+
+	fileID := GetFileID(mix.Game)
+	lmdID := LmdFileID(mix.Game)
+	hasLmd := mix.IndexByID(lmdID) != -1
+	descID := fileID(DescriptionsFilename)
+	hasDescriptions := mix.IndexByID(descID) != -1
+	described := mix.readDescriptions()
+
+	removed := map[uint32]bool{}
+	for _, id := range remove {
+		removed[id] = true
+	}
+	for _, f := range files {
+		removed[fileID(f.Name())] = true
+	}
+
+	var names []string
+	var descriptions []GmdEntry
+	count := 0
+
+	for i, entry := range mix.Entries {
+		if removed[entry.ID] || (hasLmd && entry.ID == lmdID) || (hasDescriptions && entry.ID == descID) {
+			continue
+		}
+		w.Copy(mix, i)
+		count++
+		if entry.Name != "" {
+			names = append(names, entry.Name)
+		}
+		if d, ok := described[entry.ID]; ok {
+			descriptions = append(descriptions, d)
+		}
+	}
+
+	for _, f := range files {
+		w.Add(f)
+		count++
+		if _, ok := FilenameIsID(f.Name()); !ok {
+			names = append(names, f.Name())
+			if d, ok := described[fileID(f.Name())]; ok {
+				descriptions = append(descriptions, GmdEntry{f.Name(), d.Description})
+			}
+		}
+	}
+
+	if hasDescriptions && !removed[descID] {
+		if f, err := writeDescriptions(descriptions); err != nil {
+			return err
+		} else if f != nil {
+			w.Add(f)
+			count++
+			names = append(names, DescriptionsFilename)
+		}
+	}
+
+	if hasLmd && !removed[lmdID] {
+		if lmd, err := writeLmd(mix.Game, names, count); err != nil {
+			return err
+		} else {
+			w.Add(lmd)
+		}
+	}
+
+	return w.Close()
+}
+
+// newWriter returns a Writer with the header format, flags and key source of the mix file.
+func (mix *Reader) newWriter(dst io.Writer) *Writer {
+	w := NewWriter(dst, mix.headerGame())
+	w.Flags = mix.Flags
+	w.KeySource = mix.KeySource
+	return w
+}
+
+// headerGame returns a game that selects the header format of the mix file.
+func (mix *Reader) headerGame() Game {
+	if mix.flagless {
+		return GameCC1
+	} else if mix.Game == GameCC1 {
+		return GameRA1
+	}
+	return mix.Game
+}
@@ -0,0 +1,59 @@
+package mix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := packBuffer(t, GameRA2, FlagChecksum|FlagEncrypted, files...)
+	if err := src.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	added := []File{
+		&bufferFile{name: "rules.ini", buffer: *bytes.NewBufferString("[General]")},
+		&bufferFile{name: "image.pcx", buffer: *bytes.NewBufferString("replaced")},
+	}
+
+	var b bytes.Buffer
+	if err := src.Edit(&b, added, []uint32{FileIDV2("scenario.ini")}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if dst.Flags != src.Flags || !bytes.Equal(dst.KeySource, src.KeySource) {
+		t.Fatal("flags or key source changed")
+	} else if err := dst.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"rules.ini": "[General]",
+		"image.pcx": "replaced",
+		"5tnk.shp":  "",
+	}
+
+	if len(dst.Entries) != len(expected)+1 {
+		t.Fatal(len(dst.Entries))
+	}
+
+	for i, entry := range dst.Entries {
+		if entry.Name == "" {
+			t.Fatalf("%08X is unnamed", entry.ID)
+		} else if contents, ok := expected[entry.Name]; !ok || entry.Name == "5tnk.shp" {
+			continue
+		} else if actual, err := io.ReadAll(dst.OpenFile(i)); err != nil {
+			t.Fatal(err)
+		} else if string(actual) != contents {
+			t.Fatalf("%s: unexpected contents", entry.Name)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package mix
 
 import (
 	"encoding/binary"
@@ -7,9 +7,15 @@ import (
 	"strings"
 )
 
-type fileID func(name string) uint32
+// FileID computes the ID of a file name.
+type FileID func(name string) uint32
 
-func filenameIsID(name string) (uint32, bool) {
+// FilenameIsID reports whether name is an eight digit hexadecimal ID, optionally followed by an extension
+// as in 0A1B2C3D.shp, and returns it.
+func FilenameIsID(name string) (uint32, bool) {
+	if len(name) > 9 && name[8] == '.' && !strings.Contains(name[9:], ".") {
+		name = name[:8]
+	}
 	if len(name) == 8 {
 		decoded, err := hex.DecodeString(name)
 		if err != nil {
@@ -20,8 +26,9 @@ func filenameIsID(name string) (uint32, bool) {
 	return 0, false
 }
 
-func fileIDV1(name string) uint32 {
-	if id, ok := filenameIsID(name); ok {
+// FileIDV1 computes the file ID used by cc1 and ra1.
+func FileIDV1(name string) uint32 {
+	if id, ok := FilenameIsID(name); ok {
 		return id
 	}
 
@@ -76,8 +83,9 @@ var adlerTable = &crc32.Table{
 	0xb3667a2e, 0xc4614ab8, 0x5d681b02, 0x2a6f2b94, 0xb40bbe37, 0xc30c8ea1, 0x5a05df1b, 0x2d02ef8d,
 }
 
-func fileIDV2(name string) uint32 {
-	if id, ok := filenameIsID(name); ok {
+// FileIDV2 computes the file ID used by cc2 and ra2.
+func FileIDV2(name string) uint32 {
+	if id, ok := FilenameIsID(name); ok {
 		return id
 	}
 
@@ -100,9 +108,10 @@ func fileIDV2(name string) uint32 {
 	return crc32.Update(0, adlerTable, []byte(name))
 }
 
-func getFileID(gameID gameID) fileID {
-	if gameID <= gameRA1 {
-		return fileIDV1
+// GetFileID returns the file ID function of a game.
+func GetFileID(game Game) FileID {
+	if game <= GameRA1 {
+		return FileIDV1
 	}
-	return fileIDV2
+	return FileIDV2
 }
@@ -1,4 +1,4 @@
-package main
+package mix
 
 import "testing"
 
@@ -11,13 +11,14 @@ func Test_fileIdV1(t *testing.T) {
 		{"rules.ini", 0xB1C3B238},
 		{"harv.shp", 0xFCECD5BE},
 		{"CAFEBABE", 0xCAFEBABE},
+		{"CAFEBABE.shp", 0xCAFEBABE},
 		{"image.pcx", 0xA3A59207},
 		{"5tnk.shp", 0xE6E4FB98},
 		{"scenario.ini", 0x20F5FAFD},
 	}
 
 	for _, test := range tests {
-		if fileIDV1(test.name) != test.id {
+		if FileIDV1(test.name) != test.id {
 			t.Fatal(test.name)
 		}
 	}
@@ -32,10 +33,11 @@ func Test_fileIdV2(t *testing.T) {
 		{"rules.ini", 0xF025A96C},
 		{"harv.vxl", 0xAEE7BB83},
 		{"CAFEBABE", 0xCAFEBABE},
+		{"CAFEBABE.shp", 0xCAFEBABE},
 	}
 
 	for _, test := range tests {
-		if fileIDV2(test.name) != test.id {
+		if FileIDV2(test.name) != test.id {
 			t.Fatal(test.name)
 		}
 	}
@@ -0,0 +1,117 @@
+package mix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File is a file to be packed.
+type File interface {
+	Name() string
+	Size() int64
+	Open() (io.ReadCloser, error)
+}
+
+type systemFile struct {
+	path string
+	size int64
+}
+
+func (info *systemFile) Name() string {
+	return filepath.Base(info.path)
+}
+
+func (info *systemFile) Size() int64 {
+	return info.size
+}
+
+func (info *systemFile) Open() (io.ReadCloser, error) {
+	return os.Open(info.path)
+}
+
+type bufferFile struct {
+	name   string
+	buffer bytes.Buffer
+}
+
+func (info *bufferFile) Name() string {
+	return info.name
+}
+
+func (info *bufferFile) Size() int64 {
+	return int64(info.buffer.Len())
+}
+
+func (info *bufferFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(&info.buffer), nil
+}
+
+type sectionFile struct {
+	name    string
+	section *io.SectionReader
+}
+
+func (info *sectionFile) Name() string {
+	return info.name
+}
+
+func (info *sectionFile) Size() int64 {
+	return info.section.Size()
+}
+
+func (info *sectionFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(info.section, 0, info.section.Size())), nil
+}
+
+// StatFile returns the file at path.
+func StatFile(path string) (File, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	return &systemFile{
+		path: path,
+		size: fi.Size(),
+	}, nil
+}
+
+// ReadDir lists the regular files in a directory, excluding the local mix database.
+func ReadDir(dirname string) ([]File, error) {
+	fi1, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	var fi2 []File
+	for _, fi := range fi1 {
+		if !fi.IsDir() && strings.ToLower(fi.Name()) != LmdFilename {
+			fi2 = append(fi2, &systemFile{
+				size: fi.Size(),
+				path: path.Join(dirname, fi.Name()),
+			})
+		}
+	}
+	return fi2, nil
+}
+
+// ListFilesToPack lists the files in a directory and optionally adds a local mix database.
+func ListFilesToPack(dirname string, database bool, game Game) ([]File, error) {
+	if files, err := ReadDir(dirname); err != nil {
+		return nil, err
+	} else if database {
+		lmb, err := WriteLmd(game, files)
+		if err != nil {
+			return nil, err
+		}
+		return append(files, lmb), nil
+	} else {
+		return files, nil
+	}
+}
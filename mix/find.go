@@ -0,0 +1,73 @@
+package mix
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ParseID parses an ID written as eight hexadecimal digits with an optional 0x prefix.
+func ParseID(s string) (uint32, bool) {
+	if len(s) == 10 && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")) {
+		if id, err := strconv.ParseUint(s[2:], 16, 32); err == nil {
+			return uint32(id), true
+		}
+		return 0, false
+	}
+	return FilenameIsID(s)
+}
+
+// Match is an entry found by Find.
+type Match struct {
+	// Index is the index of the entry.
+	Index int
+	// Name is the name the entry was found by, which is the pattern if it is a file name
+	// and the name of the entry otherwise.
+	Name string
+}
+
+// Find returns the entries that match pattern.
+// The pattern is either an ID, a file name or a glob as accepted by path.Match.
+// IDs and file names match every entry with that ID. File names are hashed so that
+// entries are found even if they are unnamed. Globs are matched case-insensitively
+// against the names of the entries and against the IDs of unnamed entries formatted as %08X.
+// Find does not modify the entries.
+func (mix *Reader) Find(pattern string) ([]Match, error) {
+	if id, ok := ParseID(pattern); ok {
+		return mix.findID(id, ""), nil
+	} else if !strings.ContainsAny(pattern, "*?[\\") {
+		return mix.findID(GetFileID(mix.Game)(pattern), pattern), nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for i, entry := range mix.Entries {
+		name := entry.Name
+		if name == "" {
+			name = fmt.Sprintf("%08X", entry.ID)
+		}
+		if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+			matches = append(matches, Match{i, entry.Name})
+		}
+	}
+	return matches, nil
+}
+
+func (mix *Reader) findID(id uint32, name string) []Match {
+	var matches []Match
+	for i, entry := range mix.Entries {
+		if entry.ID == id {
+			if name != "" {
+				matches = append(matches, Match{i, name})
+			} else {
+				matches = append(matches, Match{i, entry.Name})
+			}
+		}
+	}
+	return matches
+}
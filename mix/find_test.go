@@ -0,0 +1,52 @@
+package mix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	files, err := ReadDir("../test/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix := packBuffer(t, GameRA1, 0, files...)
+
+	find := func(pattern string) []Match {
+		matches, err := mix.Find(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	if matches := find("*.shp"); len(matches) != 0 {
+		t.Fatal("unnamed entries matched by name")
+	} else if matches := find("SCENARIO.INI"); len(matches) != 1 {
+		t.Fatal(matches)
+	} else if matches[0].Name != "SCENARIO.INI" {
+		t.Fatal(matches[0].Name)
+	} else if mix.Entries[matches[0].Index].Name != "" {
+		t.Fatal("entry was named")
+	} else if matches := find("*.ini"); len(matches) != 0 {
+		t.Fatal(matches)
+	} else if matches := find("0xE6E4FB98"); len(matches) != 1 {
+		t.Fatal(matches)
+	} else if matches := find("A3A59207"); len(matches) != 1 {
+		t.Fatal(matches)
+	} else if matches := find("*"); len(matches) != 3 {
+		t.Fatal(matches)
+	} else if matches := find("missing.ini"); len(matches) != 0 {
+		t.Fatal(matches)
+	} else if _, err := mix.Find("[a-"); err == nil {
+		t.Fatal("expected bad pattern")
+	}
+
+	i := find("SCENARIO.INI")[0].Index
+	mix.Entries = append(mix.Entries, mix.Entries[i])
+	if matches := find("SCENARIO.INI"); len(matches) != 2 {
+		t.Fatal(matches)
+	} else if matches := find(fmt.Sprintf("%08X", mix.Entries[i].ID)); len(matches) != 2 {
+		t.Fatal(matches)
+	}
+}
@@ -0,0 +1,276 @@
+package mix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+var errIsDir = errors.New("is a directory")
+
+// FS is a read-only file system of the entries of a mix file.
+// Entries are named by their resolved names or by their IDs formatted as %08X.
+// It implements fs.ReadDirFS, fs.StatFS and fs.ReadFileFS.
+type FS struct {
+	reader  *Reader
+	entries []fsEntry
+	names   map[string]int
+}
+
+type fsEntry struct {
+	name  string
+	index int
+	sub   *FS
+}
+
+// NewFS returns a file system of the entries of mix.
+// The names of the entries must be resolved before calling NewFS.
+// If nested is true then entries that are mix files themselves are presented as directories.
+func NewFS(mix *Reader, nested bool) *FS {
+	fsys := &FS{
+		reader: mix,
+		names:  map[string]int{},
+	}
+
+	for i, entry := range mix.Entries {
+		name := entry.Name
+		if name == "" || !fs.ValidPath(name) || strings.Contains(name, "/") {
+			name = fmt.Sprintf("%08X", entry.ID)
+		}
+		if _, exists := fsys.names[name]; exists {
+			continue
+		}
+
+		e := fsEntry{
+			name:  name,
+			index: i,
+		}
+
+		if nested {
+			if sub, ok := openNestedMix(mix, i); ok {
+				e.sub = NewFS(sub, true)
+			}
+		}
+
+		fsys.names[name] = len(fsys.entries)
+		fsys.entries = append(fsys.entries, e)
+	}
+
+	sort.Slice(fsys.entries, func(i, j int) bool {
+		return fsys.entries[i].name < fsys.entries[j].name
+	})
+
+	for i, e := range fsys.entries {
+		fsys.names[e.name] = i
+	}
+
+	return fsys
+}
+
+func openNestedMix(mix *Reader, i int) (*Reader, bool) {
+	sub, err := mix.OpenMix(i)
+	if err != nil {
+		return nil, false
+	}
+	_ = sub.ReadGmd("")
+	sub.RecoverLmd()
+	_ = sub.ReadLmd()
+	return sub, true
+}
+
+// lookup returns the file system and entry that name refers to.
+// The entry is nil if name refers to the root of the returned file system.
+func (fsys *FS) lookup(name string) (*FS, *fsEntry, bool) {
+	if name == "." {
+		return fsys, nil, true
+	}
+
+	elem, rest := name, ""
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		elem, rest = name[:i], name[i+1:]
+	}
+
+	if i, ok := fsys.names[elem]; !ok {
+		return nil, nil, false
+	} else if e := &fsys.entries[i]; rest == "" {
+		return fsys, e, true
+	} else if e.sub == nil {
+		return nil, nil, false
+	} else {
+		return e.sub.lookup(rest)
+	}
+}
+
+func (fsys *FS) stat(name string) (fs.FileInfo, *FS, *fsEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, nil, fs.ErrInvalid
+	} else if parent, e, ok := fsys.lookup(name); !ok {
+		return nil, nil, nil, fs.ErrNotExist
+	} else if e == nil {
+		return &fileInfo{name: pathBase(name), dir: true}, parent, nil, nil
+	} else {
+		return parent.entryInfo(e), parent, e, nil
+	}
+}
+
+func (fsys *FS) entryInfo(e *fsEntry) *fileInfo {
+	return &fileInfo{
+		name: e.name,
+		size: int64(fsys.reader.Entries[e.index].Size),
+		dir:  e.sub != nil,
+	}
+}
+
+// Open opens the named file or directory.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	info, parent, e, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	} else if e == nil {
+		return &dir{fsys: parent, info: info, path: name}, nil
+	} else if e.sub != nil {
+		return &dir{fsys: e.sub, info: info, path: name}, nil
+	} else {
+		return &file{SectionReader: parent.reader.OpenFile(e.index), info: info}, nil
+	}
+}
+
+// Stat returns the file info of the named file or directory.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	info, _, _, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, parent, e, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	} else if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	} else if e != nil {
+		parent = e.sub
+	}
+	return parent.dirEntries(), nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	info, parent, e, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	} else if info.IsDir() {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errIsDir}
+	}
+	buf := make([]byte, info.Size())
+	if _, err := io.ReadFull(parent.reader.OpenFile(e.index), buf); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return buf, nil
+}
+
+func (fsys *FS) dirEntries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(fsys.entries))
+	for i := range fsys.entries {
+		entries[i] = fsys.entryInfo(&fsys.entries[i])
+	}
+	return entries
+}
+
+func pathBase(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (info *fileInfo) Name() string {
+	return info.name
+}
+
+func (info *fileInfo) Size() int64 {
+	return info.size
+}
+
+func (info *fileInfo) Mode() fs.FileMode {
+	if info.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (info *fileInfo) Type() fs.FileMode {
+	return info.Mode().Type()
+}
+
+func (info *fileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (info *fileInfo) IsDir() bool {
+	return info.dir
+}
+
+func (info *fileInfo) Sys() interface{} {
+	return nil
+}
+
+func (info *fileInfo) Info() (fs.FileInfo, error) {
+	return info, nil
+}
+
+type file struct {
+	*io.SectionReader
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+type dir struct {
+	fsys   *FS
+	info   fs.FileInfo
+	path   string
+	offset int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errIsDir}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.fsys.dirEntries()[d.offset:]
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	} else if n > 0 && n < len(entries) {
+		entries = entries[:n]
+	}
+	d.offset += len(entries)
+	return entries, nil
+}
@@ -0,0 +1,68 @@
+package mix
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func packBytes(t *testing.T, game Game, flags uint32, files ...File) []byte {
+	var b bytes.Buffer
+	w := NewWriter(&b, game)
+	w.Flags = flags
+	w.Add(files...)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func packBuffer(t *testing.T, game Game, flags uint32, files ...File) *Reader {
+	b := packBytes(t, game, flags, files...)
+	mix, err := NewReader(bytes.NewReader(b), int64(len(b)), game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mix
+}
+
+func TestFS(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := packBytes(t, GameRA1, FlagEncrypted, files...)
+
+	outerFiles := []File{
+		&bufferFile{name: "inner.mix", buffer: *bytes.NewBuffer(inner)},
+		&bufferFile{name: "readme.txt", buffer: *bytes.NewBufferString("hello")},
+		&bufferFile{name: "CAFEBABE", buffer: *bytes.NewBufferString("unnamed")},
+	}
+	lmd, err := WriteLmd(GameRA1, outerFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := packBuffer(t, GameRA1, FlagChecksum, append(outerFiles, lmd)...)
+	if err := outer.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	flat := NewFS(outer, false)
+	if err := fstest.TestFS(flat, "inner.mix", "readme.txt", "CAFEBABE", LmdFilename); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := NewFS(outer, true)
+	if err := fstest.TestFS(nested, "inner.mix/5tnk.shp", "inner.mix/scenario.ini", "readme.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := fs.ReadFile(nested, "readme.txt"); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "hello" {
+		t.Fatal(string(b))
+	} else if _, err := fs.ReadFile(nested, "inner.mix"); err == nil {
+		t.Fatal("expected error reading directory")
+	}
+}
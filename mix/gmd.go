@@ -0,0 +1,159 @@
+package mix
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+//go:embed cc1gmd.csv cc2gmd.csv ra1gmd.csv ra2gmd.csv
+var gmdfs embed.FS
+
+// xccGames are the games of the sections of XCC's global mix database.dat, in order.
+var xccGames = []Game{GameCC1, GameRA1, GameCC2, GameRA2}
+
+var errXccGmd = errors.New("invalid global mix database.dat")
+
+// GmdEntry is a name and its description in a global mix database.
+type GmdEntry struct {
+	Name        string
+	Description string
+}
+
+// ReadGmd reads a global mix database and maps file IDs to names. See ReadGmdEntries.
+func ReadGmd(filename string, gameid Game) (map[uint32]string, error) {
+	entries, err := ReadGmdEntries(filename, gameid)
+	if err != nil {
+		return nil, err
+	}
+
+	fileid := GetFileID(gameid)
+
+	mapper := make(map[uint32]string)
+	for _, entry := range entries {
+		mapper[fileid(entry.Name)] = entry.Name
+	}
+	return mapper, nil
+}
+
+// ReadGmdEntries reads the names and descriptions of a game from a global mix database.
+// The database is either tab separated or XCC's binary global mix database.dat,
+// which is recognized by its contents.
+// The database embedded for the game is read if filename is empty.
+func ReadGmdEntries(filename string, gameid Game) ([]GmdEntry, error) {
+	var f fs.File
+
+	if filename == "" {
+		emf, err := gmdfs.Open(fmt.Sprintf("%sgmd.csv", gameid))
+		if err != nil {
+			return nil, err
+		}
+		f = emf
+	} else {
+		osf, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		f = osf
+	}
+
+	defer f.Close()
+	return readGmdEntries(f, gameid)
+}
+
+// readGmdEntries reads the names and descriptions of a game from a global mix database in either format.
+func readGmdEntries(r io.Reader, gameid Game) ([]GmdEntry, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(4); bytes.IndexByte(head, 0) >= 0 {
+		return readXccGmd(br, gameid)
+	}
+
+	var entries []GmdEntry
+
+	c := csv.NewReader(br)
+	c.ReuseRecord = true
+	c.Comma = '\t'
+	c.FieldsPerRecord = -1
+
+	for {
+		rec, err := c.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		entry := GmdEntry{Name: rec[0]}
+		if len(rec) > 1 {
+			entry.Description = rec[1]
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// readXccGmd reads the section of a game from XCC's global mix database.dat.
+// Each section is a count followed by that many pairs of a name and a description,
+// which are terminated by zeros.
+func readXccGmd(r *bufio.Reader, gameid Game) ([]GmdEntry, error) {
+	for _, game := range xccGames {
+		count, err := readUint32(r)
+		if err != nil {
+			return nil, errXccGmd
+		}
+
+		var entries []GmdEntry
+		for i := uint32(0); i < count; i++ {
+			name, err1 := r.ReadString(0)
+			description, err2 := r.ReadString(0)
+			if err1 != nil || err2 != nil {
+				return nil, errXccGmd
+			}
+			if game == gameid {
+				entries = append(entries, GmdEntry{
+					Name:        name[:len(name)-1],
+					Description: description[:len(description)-1],
+				})
+			}
+		}
+
+		if game == gameid {
+			return entries, nil
+		}
+	}
+	return nil, fmt.Errorf("global mix database.dat has no names for %s", gameid)
+}
+
+// WriteXccGmd writes the entries of each game in XCC's global mix database.dat format.
+// Games without entries get empty sections.
+func WriteXccGmd(w io.Writer, entries map[Game][]GmdEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, game := range xccGames {
+		if _, err := writeUint32(bw, uint32(len(entries[game]))); err != nil {
+			return err
+		}
+		for _, entry := range entries[game] {
+			if _, err := fmt.Fprintf(bw, "%s\x00%s\x00", entry.Name, entry.Description); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteGmd writes entries as a tab separated global mix database.
+func WriteGmd(w io.Writer, entries []GmdEntry) error {
+	c := csv.NewWriter(w)
+	c.Comma = '\t'
+	for _, entry := range entries {
+		if err := c.Write([]string{entry.Name, entry.Description}); err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
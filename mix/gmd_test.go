@@ -0,0 +1,94 @@
+package mix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXccGmd(t *testing.T) {
+	entries := map[Game][]GmdEntry{}
+	for _, game := range []Game{GameCC1, GameRA1, GameRA2} {
+		es, err := ReadGmdEntries("", game)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(es) == 0 {
+			t.Fatalf("%s: no entries", game)
+		}
+		entries[game] = es
+	}
+
+	var b bytes.Buffer
+	if err := WriteXccGmd(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "global mix database.dat")
+	if err := os.WriteFile(filename, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, game := range []Game{GameCC1, GameRA1, GameCC2, GameRA2} {
+		es, err := ReadGmdEntries(filename, game)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(es) != len(entries[game]) {
+			t.Fatalf("%s: expected %d entries but got %d", game, len(entries[game]), len(es))
+		}
+		for i := range es {
+			if es[i] != entries[game][i] {
+				t.Fatalf("%s: expected %+v but got %+v", game, entries[game][i], es[i])
+			}
+		}
+	}
+
+	csvname := filepath.Join(t.TempDir(), "ra1.csv")
+	if f, err := os.Create(csvname); err != nil {
+		t.Fatal(err)
+	} else if err := WriteGmd(f, entries[GameRA1]); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	} else if es, err := ReadGmdEntries(csvname, GameRA1); err != nil {
+		t.Fatal(err)
+	} else if len(es) != len(entries[GameRA1]) || es[1] != entries[GameRA1][1] {
+		t.Fatalf("expected %+v but got %+v", entries[GameRA1][1], es[1])
+	}
+
+	names, err := ReadGmd(filename, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	} else if names[FileIDV1("120mm.shp")] != "120MM.SHP" {
+		t.Fatalf("120mm.shp is not named: %q", names[FileIDV1("120mm.shp")])
+	}
+
+	if err := os.WriteFile(filename, b.Bytes()[:b.Len()/2], 0644); err != nil {
+		t.Fatal(err)
+	} else if _, err := ReadGmd(filename, GameRA2); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestXccGmdBufferBoundary(t *testing.T) {
+	// the count of the ra1 section starts 2 bytes before the end of the 4 KiB read buffer
+	entries := map[Game][]GmdEntry{
+		GameCC1: {{Name: strings.Repeat("a", 4094-4-2)}},
+		GameRA1: {{Name: "rules.ini", Description: "desc"}},
+	}
+	var b bytes.Buffer
+	if err := WriteXccGmd(&b, entries); err != nil {
+		t.Fatal(err)
+	} else if b.Bytes()[4094] != 1 {
+		t.Fatal("the ra1 count is not at the buffer boundary")
+	}
+
+	filename := filepath.Join(t.TempDir(), "global mix database.dat")
+	if err := os.WriteFile(filename, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	} else if es, err := ReadGmdEntries(filename, GameRA1); err != nil {
+		t.Fatal(err)
+	} else if len(es) != 1 || es[0] != entries[GameRA1][0] {
+		t.Fatalf("expected %+v but got %+v", entries[GameRA1], es)
+	}
+}
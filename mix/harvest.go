@@ -0,0 +1,114 @@
+package mix
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// harvestSuffixes are appended to the words found in ini files before the extensions,
+// for the build up animations, cameos, turrets and barrels that are named after an object.
+var harvestSuffixes = []string{"", "icon", "make", "tur", "barl"}
+
+// harvestWords adds the section names and the comma separated values of the ini file in r to words.
+func harvestWords(r io.Reader, words map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			addHarvestWord(words, line[1:len(line)-1])
+		} else if i := strings.IndexByte(line, '='); i >= 0 {
+			for _, word := range strings.Split(line[i+1:], ",") {
+				addHarvestWord(words, word)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// addHarvestWord adds word to words if it could be part of a file name.
+func addHarvestWord(words map[string]bool, word string) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if len(word) == 0 || len(word) > 64 {
+		return
+	}
+	letter := false
+	for _, c := range word {
+		if c >= 'a' && c <= 'z' {
+			letter = true
+		} else if (c < '0' || c > '9') && c != '_' && c != '-' && c != '.' {
+			return
+		}
+	}
+	if letter {
+		words[word] = true
+	}
+}
+
+// isIni reports whether the i-th entry is an ini file by its name or, if unnamed and sniff is true, by its contents.
+func (mix *Reader) isIni(i int, sniff bool) bool {
+	if name := mix.Entries[i].Name; name != "" {
+		return strings.HasSuffix(strings.ToLower(name), ".ini")
+	}
+	return sniff && mix.FileType(i) == "ini"
+}
+
+// HarvestNames names unnamed entries after the objects, images and sounds that ini files refer to,
+// such as rules.ini, art.ini, sound.ini and theme.ini.
+// The ini files are the entries of mix that are named or recognized as ini files
+// and the entries of sources, such as sibling mix files, that are named as ini files.
+// The section names and the values of the ini files are tried as file names
+// with each of Extensions and with the suffixes for build up animations, cameos, turrets and barrels.
+// It returns the number of entries that were named.
+func (mix *Reader) HarvestNames(sources ...*Reader) int {
+	unknown := map[uint32][]int{}
+	for i, entry := range mix.Entries {
+		if entry.Name == "" {
+			unknown[entry.ID] = append(unknown[entry.ID], i)
+		}
+	}
+	if len(unknown) == 0 {
+		return 0
+	}
+
+	words := map[string]bool{}
+	for k, r := range append([]*Reader{mix}, sources...) {
+		for i := range r.Entries {
+			if r.isIni(i, k == 0) {
+				_ = harvestWords(r.OpenFile(i), words)
+			}
+		}
+	}
+
+	fileID := GetFileID(mix.Game)
+	named := 0
+	try := func(name string) {
+		if _, ok := FilenameIsID(name); ok {
+			return
+		}
+		id := fileID(name)
+		for _, i := range unknown[id] {
+			mix.Entries[i].Name = name
+			mix.Entries[i].Source = SourceIni
+			named++
+		}
+		delete(unknown, id)
+	}
+
+	for word := range words {
+		if strings.Contains(word, ".") {
+			try(word)
+			continue
+		}
+		for _, suffix := range harvestSuffixes {
+			for _, ext := range Extensions {
+				try(word + suffix + "." + ext)
+			}
+		}
+	}
+	return named
+}
@@ -0,0 +1,41 @@
+package mix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHarvestNames(t *testing.T) {
+	for _, game := range []Game{GameRA1, GameRA2} {
+		files, err := ListFilesToPack("../test/files", false, game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules := "; rules\r\n[5TNK]\r\nImage=Image\r\nPrimary=105mm ; gun\r\n"
+		files = append(files, &bufferFile{name: "rules.ini", buffer: *bytes.NewBufferString(rules)})
+
+		// the ini files are found by their contents
+		mix := packBuffer(t, game, 0, files...)
+		if n := mix.HarvestNames(); n != 2 {
+			t.Fatalf("%s: expected 2 names but got %d: %+v", game, n, mix.Entries)
+		}
+		for _, entry := range mix.Entries {
+			switch entry.Name {
+			case "5tnk.shp", "image.pcx", "":
+			default:
+				t.Fatalf("%s: unexpected entry %+v", game, entry)
+			}
+			if entry.Name != "" && GetFileID(game)(entry.Name) != entry.ID {
+				t.Fatalf("%s: wrong name %+v", game, entry)
+			}
+		}
+
+		// or by their names in another mix file
+		source := packBuffer(t, game, 0, &bufferFile{name: "rules.ini", buffer: *bytes.NewBufferString(rules)})
+		source.Entries[0].Name = "rules.ini"
+		mix = packBuffer(t, game, 0, files[:len(files)-1]...)
+		if n := mix.HarvestNames(source); n != 2 {
+			t.Fatalf("%s: expected 2 names but got %d: %+v", game, n, mix.Entries)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package mix
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of regions reported by Layout.
+const (
+	RegionFlags        = "flags"
+	RegionKeySource    = "key-source"
+	RegionCount        = "count"
+	RegionBodySize     = "body-size"
+	RegionIndexEntry   = "index-entry"
+	RegionIndexBlock   = "index-block"
+	RegionIndexPadding = "index-padding"
+	RegionEntry        = "entry"
+	RegionGap          = "gap"
+	RegionOverlap      = "overlap"
+	RegionChecksum     = "checksum"
+	RegionTruncated    = "truncated"
+	RegionTrailing     = "trailing-data"
+)
+
+// Region is a range of bytes of a mix file reported by Layout.
+type Region struct {
+	// Kind is one of the Region constants.
+	Kind string `json:"kind"`
+	// Offset is the offset of the region from the start of the file.
+	Offset int64 `json:"offset"`
+	// Size is the length of the region in bytes.
+	Size int64 `json:"size"`
+	// Entry is the index of the entry concerned or -1.
+	Entry int `json:"entry"`
+	// Description describes the contents of the region.
+	Description string `json:"description"`
+}
+
+// Layout maps the bytes of the mix file to the header fields, the entries,
+// the gaps and overlaps between entries, the checksum and any trailing data.
+// Regions are sorted by offset. Overlap regions cover bytes that are also covered by entries,
+// and entries that extend beyond the body may extend beyond the end of the file.
+func (mix *Reader) Layout() ([]Region, error) {
+	var regions []Region
+
+	add := func(kind string, entry int, offset, size int64, format string, args ...interface{}) {
+		regions = append(regions, Region{
+			Kind:        kind,
+			Offset:      offset,
+			Size:        size,
+			Entry:       entry,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	mix.headerLayout(add)
+
+	v, err := mix.Verify()
+	if err != nil {
+		return nil, err
+	}
+
+	body := int64(mix.BodyOffset)
+	fileSize := mix.reader.Size()
+	bodyEnd := body + int64(mix.DeclaredBodySize)
+	if v.Truncated {
+		bodyEnd = fileSize
+	}
+
+	var inBody []int
+	for i, entry := range mix.Entries {
+		offset, size := body+int64(entry.Offset), int64(entry.Size)
+		if offset+size > bodyEnd {
+			add(RegionEntry, i, offset, size, "%s extends beyond the body", entryDescription(entry))
+		} else {
+			add(RegionEntry, i, offset, size, "%s", entryDescription(entry))
+			if size != 0 {
+				inBody = append(inBody, i)
+			}
+		}
+	}
+
+	sort.SliceStable(inBody, func(a, b int) bool {
+		return mix.Entries[inBody[a]].Offset < mix.Entries[inBody[b]].Offset
+	})
+
+	end := body
+	var covering []int
+	for _, i := range inBody {
+		offset := body + int64(mix.Entries[i].Offset)
+		if offset > end {
+			add(RegionGap, -1, end, offset-end, "%d unused bytes", offset-end)
+		}
+
+		var overlapping []int
+		for _, j := range covering {
+			if jend := body + int64(mix.Entries[j].Offset) + int64(mix.Entries[j].Size); jend > offset {
+				overlapping = append(overlapping, j)
+			}
+		}
+		overlapping = append(overlapping, i)
+		if len(overlapping) > 1 {
+			oend := offset
+			for _, j := range overlapping[:len(overlapping)-1] {
+				if jend := body + int64(mix.Entries[j].Offset) + int64(mix.Entries[j].Size); jend > oend {
+					oend = jend
+				}
+			}
+			if iend := offset + int64(mix.Entries[i].Size); iend < oend {
+				oend = iend
+			}
+			add(RegionOverlap, i, offset, oend-offset, "shared by entries %s", joinInts(overlapping))
+		}
+		covering = overlapping
+
+		if iend := offset + int64(mix.Entries[i].Size); iend > end {
+			end = iend
+		}
+	}
+
+	if bodyEnd > end {
+		add(RegionGap, -1, end, bodyEnd-end, "%d unused bytes", bodyEnd-end)
+	}
+
+	if v.Truncated {
+		missing := body + int64(mix.DeclaredBodySize) - fileSize
+		add(RegionTruncated, -1, fileSize, missing, "%d bytes of the declared body are missing", missing)
+	} else if v.Expected != nil {
+		add(RegionChecksum, -1, bodyEnd, int64(len(v.Expected)), "SHA1 %x", v.Expected)
+	}
+
+	if v.Trailing > 0 {
+		add(RegionTrailing, -1, fileSize-v.Trailing, v.Trailing, "%d bytes after the end of the mix file", v.Trailing)
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Offset < regions[j].Offset
+	})
+
+	return regions, nil
+}
+
+// headerLayout adds the regions of the header.
+func (mix *Reader) headerLayout(add func(kind string, entry int, offset, size int64, format string, args ...interface{})) {
+	count := len(mix.Entries)
+
+	if mix.flagless {
+		add(RegionCount, -1, 0, 2, "%d entries", mix.DeclaredCount)
+		add(RegionBodySize, -1, 2, 4, "%d bytes", mix.DeclaredBodySize)
+	} else {
+		add(RegionFlags, -1, 0, 4, "%08X", mix.Flags)
+	}
+
+	if (mix.Flags & FlagEncrypted) == 0 {
+		if !mix.flagless {
+			add(RegionCount, -1, 4, 2, "%d entries", mix.DeclaredCount)
+			add(RegionBodySize, -1, 6, 4, "%d bytes", mix.DeclaredBodySize)
+		}
+		for i, entry := range mix.Entries {
+			add(RegionIndexEntry, i, mix.indexOffset(i), 12, "%08X offset %d size %d", entry.ID, entry.Offset, entry.Size)
+		}
+		return
+	}
+
+	add(RegionKeySource, -1, 4, 80, "%x", mix.KeySource)
+
+	// The decrypted index is the count, the body size and the entries, padded to a whole number of blocks.
+	const blockSize = 8
+	indexSize := 6 + 12*int64(count)
+	paddedSize := (indexSize + blockSize - 1) &^ (blockSize - 1)
+
+	field := func(pos int64) string {
+		if pos < 2 {
+			return "count"
+		} else if pos < 6 {
+			return "body size"
+		} else if pos < indexSize {
+			i := (pos - 6) / 12
+			return fmt.Sprintf("entry %d (%08X)", i, mix.Entries[i].ID)
+		}
+		return "padding"
+	}
+
+	for pos := int64(0); pos < paddedSize; pos += blockSize {
+		var fields []string
+		for p := pos; p < pos+blockSize; p++ {
+			if f := field(p); len(fields) == 0 || fields[len(fields)-1] != f {
+				fields = append(fields, f)
+			}
+		}
+		add(RegionIndexBlock, -1, 84+pos, blockSize, "%s", strings.Join(fields, ", "))
+	}
+
+	if paddedSize > indexSize {
+		add(RegionIndexPadding, -1, 84+indexSize, paddedSize-indexSize, "%d bytes padding the index to a whole block", paddedSize-indexSize)
+	}
+}
+
+func entryDescription(entry Entry) string {
+	if entry.Name != "" {
+		return fmt.Sprintf("%08X %s", entry.ID, entry.Name)
+	}
+	return fmt.Sprintf("%08X", entry.ID)
+}
+
+func joinInts(xs []int) string {
+	s := make([]string, len(xs))
+	for i, x := range xs {
+		s[i] = fmt.Sprint(x)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,69 @@
+package mix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flags := range []uint32{0, FlagChecksum, FlagEncrypted, FlagChecksum | FlagEncrypted} {
+		b := packBytes(t, GameRA1, flags, files...)
+		mix, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		regions, err := mix.Layout()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A file written by Writer is covered exactly once, without gaps.
+		end := int64(0)
+		for _, r := range regions {
+			switch r.Kind {
+			case RegionGap, RegionOverlap, RegionTruncated, RegionTrailing:
+				t.Fatalf("%x: unexpected %+v", flags, r)
+			case RegionIndexPadding:
+				continue
+			}
+			if r.Offset != end {
+				t.Fatalf("%x: expected region at %d but got %+v", flags, end, r)
+			}
+			end += r.Size
+		}
+		if end != int64(len(b)) {
+			t.Fatalf("%x: regions end at %d but the file has %d bytes", flags, end, len(b))
+		}
+	}
+}
+
+func TestLayoutOverlap(t *testing.T) {
+	mix := packBuffer(t, GameRA1, 0,
+		&bufferFile{name: "a", buffer: *bytes.NewBufferString("0123456789")},
+		&bufferFile{name: "b", buffer: *bytes.NewBufferString("0123456789")},
+	)
+	mix.Entries[1].Offset = 4
+
+	regions, err := mix.Layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var overlap, gap bool
+	for _, r := range regions {
+		if r.Kind == RegionOverlap && r.Offset == int64(mix.BodyOffset)+4 && r.Size == 6 {
+			overlap = true
+		} else if r.Kind == RegionGap && r.Offset == int64(mix.BodyOffset)+14 && r.Size == 6 {
+			gap = true
+		}
+	}
+	if !overlap || !gap {
+		t.Fatalf("%+v", regions)
+	}
+}
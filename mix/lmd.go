@@ -0,0 +1,186 @@
+package mix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Game identifies one of the supported C&C games.
+type Game int
+
+func (g Game) String() string {
+	switch g {
+	case GameCC1:
+		return "cc1"
+	case GameRA1:
+		return "ra1"
+	case GameCC2:
+		return "cc2"
+	case GameRA2:
+		return "ra2"
+	default:
+		return ""
+	}
+}
+
+const (
+	GameCC1   Game = 0
+	GameRA1   Game = 1
+	GameCC2   Game = 2
+	GameRA2   Game = 5
+	lmdHeader      = "XCC by Olaf van der Spek\x1a\x04\x17\x27\x10\x19\x80\x00"
+)
+
+// LmdFilename is the name of the local mix database.
+const LmdFilename = "local mix database.dat"
+
+// DescriptionsFilename is the name of the entry that describes the other entries of a mix file.
+// It is a tab separated global mix database of the names and descriptions of the described entries.
+const DescriptionsFilename = "local mix descriptions.csv"
+
+// WriteLmd creates a local mix database of all files that are not named by ID.
+func WriteLmd(game Game, files []File) (File, error) {
+	var names []string
+	for _, f := range files {
+		if _, ok := FilenameIsID(f.Name()); !ok {
+			names = append(names, f.Name())
+		}
+	}
+	return writeLmd(game, names, len(files))
+}
+
+// writeLmd creates a local mix database of names for a mix file of count files excluding the database itself.
+func writeLmd(game Game, names []string, count int) (File, error) {
+	var b bytes.Buffer
+
+	if _, err := b.WriteString(lmdHeader); err != nil {
+		return nil, err
+	}
+
+	size := uint32(52 + 1 + len(LmdFilename))
+	for _, name := range names {
+		size += uint32(1 + len(name))
+	}
+
+	for _, v := range []uint32{size, 0, 0, uint32(game), 1 + uint32(count)} {
+		if _, err := writeUint32(&b, v); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(&b, "%s\x00", name); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := fmt.Fprintf(&b, "%s\x00", LmdFilename); err != nil {
+		return nil, err
+	}
+
+	return &bufferFile{
+		name:   LmdFilename,
+		buffer: b,
+	}, nil
+}
+
+// readLmdHeader reads the header of a local mix database up to the file names.
+func readLmdHeader(r io.ReadSeeker) (Game, error) {
+	var hdr [32]byte
+
+	if _, err := r.Read(hdr[:]); err != nil {
+		return 0, err
+	} else if string(hdr[:]) != lmdHeader {
+		return 0, errors.New("not a local mix database")
+	} else if _, err := r.Seek(12, io.SeekCurrent); err != nil {
+		return 0, err
+	} else if gameid, err := readUint32(r); err != nil {
+		return 0, err
+	} else if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+		return 0, err
+	} else {
+		return Game(gameid), nil
+	}
+}
+
+// ReadLmd reads a local mix database and maps file IDs to names.
+func ReadLmd(r io.ReadSeeker) (map[uint32]string, error) {
+	if game, err := readLmdHeader(r); err != nil {
+		return nil, err
+	} else {
+		mapper := map[uint32]string{}
+		fileID := GetFileID(game)
+
+		scanner := bufio.NewScanner(r)
+		scanner.Split(scanZStrings)
+		for scanner.Scan() {
+			filename := scanner.Text()
+			mapper[fileID(filename)] = filename
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return mapper, nil
+	}
+}
+
+// WriteDescriptions creates the descriptions entry of the files that are not named by ID
+// and that have a description in descriptions, which maps file IDs to descriptions.
+// It returns nil if none of the files has a description.
+// Readers that do not know about the entry see it as a plain file, so the mix file stays compatible with them.
+func WriteDescriptions(game Game, files []File, descriptions map[uint32]string) (File, error) {
+	fileID := GetFileID(game)
+	var entries []GmdEntry
+	for _, f := range files {
+		if _, ok := FilenameIsID(f.Name()); !ok && descriptions[fileID(f.Name())] != "" {
+			entries = append(entries, GmdEntry{f.Name(), descriptions[fileID(f.Name())]})
+		}
+	}
+	return writeDescriptions(entries)
+}
+
+// writeDescriptions creates a descriptions entry of entries, or returns nil if there are none.
+func writeDescriptions(entries []GmdEntry) (File, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	var b bytes.Buffer
+	if err := WriteGmd(&b, entries); err != nil {
+		return nil, err
+	}
+	return &bufferFile{
+		name:   DescriptionsFilename,
+		buffer: b,
+	}, nil
+}
+
+// readDescriptions maps file IDs to the names and descriptions of the descriptions entry if the mix file has one.
+func (mix *Reader) readDescriptions() map[uint32]GmdEntry {
+	i := mix.IndexByID(GetFileID(mix.Game)(DescriptionsFilename))
+	if i == -1 {
+		return nil
+	}
+	entries, err := readGmdEntries(mix.OpenFile(i), mix.Game)
+	if err != nil {
+		return nil
+	}
+	fileID := GetFileID(mix.Game)
+	descriptions := map[uint32]GmdEntry{}
+	for _, entry := range entries {
+		descriptions[fileID(entry.Name)] = entry
+	}
+	return descriptions
+}
+
+// LmdFileID returns the ID of the local mix database of a game.
+func LmdFileID(game Game) uint32 {
+	if game <= GameRA1 {
+		return 0x54C2D545
+	}
+	return 0x366E051F
+}
@@ -0,0 +1,113 @@
+package mix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLmdWrite(t *testing.T) {
+	if files, err := ReadDir("../test/files"); err != nil {
+		t.Fatal(err)
+	} else if lmd, err := WriteLmd(GameCC1, files); err != nil {
+		t.Fatal(err)
+	} else if f, err := os.OpenFile("../test/local mix database.dat", os.O_CREATE|os.O_RDWR, 0644); err != nil {
+		t.Fatal(err)
+	} else if r, err := lmd.Open(); err != nil {
+		t.Fatal(err)
+	} else if _, err := io.Copy(f, r); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	files, err := ReadDir("../test/files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := map[uint32]string{
+		FileIDV2("5tnk.shp"):  "Mammoth tank",
+		FileIDV2("image.pcx"): "Title screen",
+	}
+	described, err := WriteDescriptions(GameRA2, files, descriptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, described)
+	lmd, err := WriteLmd(GameRA2, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := packBuffer(t, GameRA2, FlagChecksum, append(files, lmd)...)
+
+	// the local mix database has version 0 and names only
+	if b, err := io.ReadAll(src.OpenFile(src.IndexByID(LmdFileID(GameRA2)))); err != nil {
+		t.Fatal(err)
+	} else if binary.LittleEndian.Uint32(b[40:]) != 0 {
+		t.Fatal("local mix database has a version")
+	} else if problems, err := src.Check(); err != nil {
+		t.Fatal(err)
+	} else if len(problems) != 0 {
+		t.Fatalf("%+v", problems)
+	}
+
+	src.RecoverLmd()
+	if err := src.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(mix *Reader) {
+		for _, entry := range mix.Entries {
+			if entry.Name == "" {
+				t.Fatalf("%08X is unnamed", entry.ID)
+			} else if entry.Description != descriptions[entry.ID] {
+				t.Fatalf("%s: expected description %q but got %q", entry.Name, descriptions[entry.ID], entry.Description)
+			}
+		}
+	}
+	check(src)
+
+	// editing keeps the descriptions
+	var b bytes.Buffer
+	added := []File{&bufferFile{name: "5tnk.shp", buffer: *bytes.NewBufferString("replaced")}}
+	if err := src.Edit(&b, added, nil); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := dst.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+	check(dst)
+
+	// deleting a file drops its description, and the entry is dropped with the last description
+	descID := FileIDV2(DescriptionsFilename)
+	for _, name := range []string{"image.pcx", "5tnk.shp"} {
+		b.Reset()
+		if err := dst.Edit(&b, nil, []uint32{FileIDV2(name)}); err != nil {
+			t.Fatal(err)
+		} else if dst, err = NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), GameRA2); err != nil {
+			t.Fatal(err)
+		} else if err := dst.ReadLmd(); err != nil {
+			t.Fatal(err)
+		}
+		delete(descriptions, FileIDV2(name))
+		if len(descriptions) == 0 {
+			if dst.IndexByID(descID) != -1 {
+				t.Fatal("empty descriptions entry was kept")
+			}
+		}
+		check(dst)
+	}
+
+	if f, err := WriteDescriptions(GameRA2, files, nil); err != nil {
+		t.Fatal(err)
+	} else if f != nil {
+		t.Fatal("expected no descriptions entry")
+	}
+}
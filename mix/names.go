@@ -0,0 +1,166 @@
+package mix
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Sources of names that are not files.
+const (
+	SourceEmbedded = "embedded"
+	SourceLmd      = "lmd"
+	SourceIni      = "ini"
+)
+
+// NameDB is an ordered stack of global mix databases of a game.
+// Later layers take precedence over earlier ones.
+type NameDB struct {
+	Game   Game
+	layers []nameLayer
+}
+
+type nameLayer struct {
+	source  string
+	entries map[uint32]GmdEntry
+}
+
+// Conflict is an ID that two sources give different names.
+// Names that differ only in case do not conflict.
+type Conflict struct {
+	ID uint32
+	// Name and Source are the name that was overridden and where it came from.
+	Name   string
+	Source string
+	// NewName and NewSource are the name that took precedence and where it came from.
+	NewName   string
+	NewSource string
+}
+
+// NewNameDB returns a stack of the embedded global mix database of game.
+func NewNameDB(game Game) (*NameDB, error) {
+	db := &NameDB{Game: game}
+	if err := db.Load(""); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Load adds the global mix database in filename on top of the stack.
+// The embedded database is added if filename is empty. See ReadGmdEntries.
+func (db *NameDB) Load(filename string) error {
+	entries, err := ReadGmdEntries(filename, db.Game)
+	if err != nil {
+		return err
+	}
+
+	source := filename
+	if source == "" {
+		source = SourceEmbedded
+	}
+
+	fileID := GetFileID(db.Game)
+	layer := nameLayer{source: source, entries: make(map[uint32]GmdEntry, len(entries))}
+	for _, entry := range entries {
+		layer.entries[fileID(entry.Name)] = entry
+	}
+	db.layers = append(db.layers, layer)
+	return nil
+}
+
+// LoadDir adds the .csv and .dat files in a directory on top of the stack in the order of their names.
+// It does nothing if the directory does not exist.
+func (db *NameDB) LoadDir(dirname string) error {
+	des, err := os.ReadDir(dirname)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, de := range des {
+		ext := strings.ToLower(filepath.Ext(de.Name()))
+		if !de.IsDir() && (ext == ".csv" || ext == ".dat") {
+			names = append(names, de.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := db.Load(filepath.Join(dirname, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the name and description of an ID from the topmost layer that has it,
+// and the description from the topmost layer that has one.
+func (db *NameDB) Lookup(id uint32) (GmdEntry, bool) {
+	var found GmdEntry
+	ok := false
+	for _, layer := range db.layers {
+		if entry, exists := layer.entries[id]; exists {
+			found.Name = entry.Name
+			if entry.Description != "" {
+				found.Description = entry.Description
+			}
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// ResolveNames names and describes the entries using the layers of db from the bottom up
+// and then the local mix database and the descriptions entry of the mix file,
+// so that later sources take precedence. The source of each name is recorded in the entry.
+// It returns the conflicts between the sources in the order in which they were found.
+func (mix *Reader) ResolveNames(db *NameDB) []Conflict {
+	var conflicts []Conflict
+
+	set := func(i int, name, description, source string) {
+		entry := &mix.Entries[i]
+		if entry.Name != "" && !strings.EqualFold(entry.Name, name) {
+			conflicts = append(conflicts, Conflict{
+				ID:        entry.ID,
+				Name:      entry.Name,
+				Source:    entry.Source,
+				NewName:   name,
+				NewSource: source,
+			})
+		}
+		entry.Name, entry.Source = name, source
+		if description != "" {
+			entry.Description = description
+		}
+	}
+
+	for _, layer := range db.layers {
+		for i, entry := range mix.Entries {
+			if e, ok := layer.entries[entry.ID]; ok {
+				set(i, e.Name, e.Description, layer.source)
+			}
+		}
+	}
+
+	if i := mix.IndexByID(LmdFileID(mix.Game)); i != -1 {
+		if names, err := ReadLmd(mix.OpenFile(i)); err == nil {
+			for i, entry := range mix.Entries {
+				if name, ok := names[entry.ID]; ok {
+					set(i, name, "", SourceLmd)
+				}
+			}
+		}
+	}
+
+	descriptions := mix.readDescriptions()
+	for i, entry := range mix.Entries {
+		if description := descriptions[entry.ID].Description; description != "" {
+			mix.Entries[i].Description = description
+		}
+	}
+
+	return conflicts
+}
@@ -0,0 +1,84 @@
+package mix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveNames(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix := packBuffer(t, GameRA1, 0, files...)
+
+	dir := t.TempDir()
+	write := func(filename, contents string) string {
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	userdir := filepath.Join(dir, "user")
+	if err := os.Mkdir(userdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	a := write(filepath.Join(userdir, "a.csv"), "IMAGE.PCX\tTitle\n5TNK.SHP\tMammoth tank\n")
+	b := write(filepath.Join(userdir, "b.csv"), "image.pcx\n")
+	write(filepath.Join(userdir, "notes.txt"), "not a database")
+	// a name that has the ID of 5tnk.shp
+	project := write(filepath.Join(dir, "project.csv"), "SCENARIO.INI\tScenario\nE6E4FB98.SHP\n")
+
+	db, err := NewNameDB(GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.LoadDir(userdir); err != nil {
+		t.Fatal(err)
+	} else if err := db.LoadDir(filepath.Join(dir, "missing")); err != nil {
+		t.Fatal(err)
+	} else if err := db.Load(project); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, ok := db.Lookup(FileIDV1("image.pcx")); !ok || e.Name != "image.pcx" || e.Description != "Title" {
+		t.Fatalf("%+v", e)
+	}
+
+	// without the local mix database
+	lmd := mix.IndexByID(LmdFileID(GameRA1))
+	lmdID := mix.Entries[lmd].ID
+	mix.Entries[lmd].ID = 0
+
+	conflicts := mix.ResolveNames(db)
+	expected := map[uint32]Entry{
+		FileIDV1("image.pcx"):    {Name: "image.pcx", Description: "Title", Source: b},
+		FileIDV1("5tnk.shp"):     {Name: "E6E4FB98.SHP", Description: "Mammoth tank", Source: project},
+		FileIDV1("scenario.ini"): {Name: "SCENARIO.INI", Description: "Scenario", Source: project},
+		0:                        {},
+	}
+	for _, entry := range mix.Entries {
+		if e := expected[entry.ID]; entry.Name != e.Name || entry.Description != e.Description || entry.Source != e.Source {
+			t.Fatalf("expected %+v but got %+v", e, entry)
+		}
+	}
+	if len(conflicts) != 1 || conflicts[0] != (Conflict{FileIDV1("5tnk.shp"), "5TNK.SHP", a, "E6E4FB98.SHP", project}) {
+		t.Fatalf("%+v", conflicts)
+	}
+
+	// the local mix database takes precedence
+	for i := range mix.Entries {
+		mix.Entries[i].Name, mix.Entries[i].Description, mix.Entries[i].Source = "", "", ""
+	}
+	mix.Entries[lmd].ID = lmdID
+
+	conflicts = mix.ResolveNames(db)
+	for _, entry := range mix.Entries {
+		if entry.Source != SourceLmd {
+			t.Fatalf("%+v", entry)
+		}
+	}
+	if len(conflicts) != 2 || conflicts[1] != (Conflict{FileIDV1("5tnk.shp"), "E6E4FB98.SHP", project, "5tnk.shp", SourceLmd}) {
+		t.Fatalf("%+v", conflicts)
+	}
+}
@@ -0,0 +1,290 @@
+// Package mix reads and writes the .mix archives used by several C&C games.
+package mix
+
+import (
+	"crypto/sha1"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+var errNotMix = errors.New("not a mix file")
+
+// Entry is an entry in the index of a mix file.
+type Entry struct {
+	ID     uint32
+	Offset uint32
+	Size   uint32
+	Name   string
+	// Description describes the contents as found in a mix database.
+	Description string
+	// Source is where the name was found, such as the path of a mix database. See ResolveNames.
+	Source string
+}
+
+// Reader reads the entries of a mix file.
+type Reader struct {
+	Entries []Entry
+	Flags   uint32
+	// BodySize is the number of bytes after the header, excluding the checksum.
+	BodySize uint32
+	// BodyOffset is the offset of the body from the start of the file.
+	BodyOffset uint32
+	// DeclaredBodySize is the body size that is written in the header.
+	DeclaredBodySize uint32
+	// DeclaredCount is the number of entries that is written in the header.
+	// It is larger than the number of entries if the index does not fit in the file.
+	DeclaredCount int
+	Game          Game
+	KeySource     []byte
+	reader        *io.SectionReader
+	// flagless is true if the header has no flags, as in cc1 mix files.
+	flagless bool
+	// emptied are the IDs of the entries that RecoverLmd emptied because they pointed beyond the body.
+	emptied map[uint32]bool
+}
+
+func readEntries(r io.Reader, count uint16) ([]Entry, error) {
+	var entries []Entry
+	for i := uint16(0); i < count; i++ {
+		if id, err := readUint32(r); err != nil {
+			return nil, err
+		} else if offset, err := readUint32(r); err != nil {
+			return nil, err
+		} else if size, err := readUint32(r); err != nil {
+			return nil, err
+		} else {
+			entries = append(entries, Entry{
+				ID:     id,
+				Offset: offset,
+				Size:   size,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// readIndex reads the body size and the entries of an index of count entries
+// in a file of fileSize bytes, where headerSize returns the size of the header of an index of n entries.
+// If the index does not fit in the file then only the entries that fit are read
+// and the index is cut where the number of plausible entries most exceeds the number of implausible ones.
+// An entry is plausible if it is not empty and lies within the body, or if it is a local mix database,
+// which protected mix files tend to move.
+func readIndex(r io.Reader, count uint16, fileSize int64, headerSize func(n int) int64) (uint32, []Entry, error) {
+	n := count
+	for n > 0 && headerSize(int(n)) > fileSize {
+		if fit := (fileSize - headerSize(0)) / 12; fit < 0 {
+			n = 0
+		} else {
+			n = uint16(min64(int64(n-1), fit))
+		}
+	}
+
+	size, err := readUint32(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	entries, err := readEntries(r, n)
+	if err != nil {
+		return 0, nil, err
+	} else if n == count {
+		return size, entries, nil
+	}
+
+	end, score, best := 0, 0, 0
+	for i, entry := range entries {
+		if entry.ID == LmdFileID(GameRA1) || entry.ID == LmdFileID(GameRA2) {
+			score++
+		} else if entry.Size != 0 && int64(entry.Offset)+int64(entry.Size) <= fileSize-headerSize(i+1) {
+			score++
+		} else {
+			score--
+		}
+		if score > best {
+			end, best = i+1, score
+		}
+	}
+	return size, entries[:end], nil
+}
+
+// bodySize returns the number of bytes after the header, excluding the checksum if there is room for one
+// after the declared body. If the file was cut short then there is no checksum.
+func bodySize(fileSize int64, offset, flags, declared uint32) uint32 {
+	size := uint32(fileSize) - offset
+	if (flags&FlagChecksum) != 0 && size >= sha1.Size && size-sha1.Size >= declared {
+		size -= sha1.Size
+	}
+	return size
+}
+
+// NewReader reads the index of the mix file in r, which is size bytes long.
+// Mix files without a flags word are read in the header format of cc1, whatever their game.
+func NewReader(ra io.ReaderAt, size int64, game Game) (*Reader, error) {
+	r := io.NewSectionReader(ra, 0, size)
+	if count, err := readUint16(r); err != nil {
+		return nil, err
+	} else if count != 0 {
+		declared, entries, err := readIndex(r, count, size, func(n int) int64 {
+			return 6 + 12*int64(n)
+		})
+		if err != nil {
+			return nil, err
+		}
+		offset := 6 + 12*uint32(len(entries))
+		return &Reader{
+			Entries:          entries,
+			Flags:            0,
+			BodySize:         uint32(r.Size()) - offset,
+			BodyOffset:       offset,
+			DeclaredBodySize: declared,
+			DeclaredCount:    int(count),
+			Game:             game,
+			reader:           r,
+			flagless:         true,
+		}, nil
+	} else if flags16, err := readUint16(r); err != nil {
+		return nil, err
+	} else {
+		flags := uint32(flags16) << 16
+
+		if (flags & FlagEncrypted) != 0 {
+			keySource := [80]byte{}
+			_, err := r.Read(keySource[:])
+			if err != nil {
+				return nil, err
+			}
+			blowfishKey := blowfishKeyFromKeySource(keySource[:])
+			cipher, err := blowfish.NewCipher(blowfishKey)
+			if err != nil {
+				return nil, err
+			}
+			ecb := newECBReader(r, cipher)
+			if count, err := readUint16(ecb); err != nil {
+				return nil, err
+			} else if declared, entries, err := readIndex(ecb, count, size, func(n int) int64 {
+				return 84 + ((6 + 12*int64(n) + 7) &^ 7)
+			}); err != nil {
+				return nil, err
+			} else {
+				offset := 84 + ((6 + 12*uint32(len(entries)) + 7) &^ 7)
+				return &Reader{
+					Entries:          entries,
+					Flags:            flags,
+					BodySize:         bodySize(r.Size(), offset, flags, declared),
+					BodyOffset:       offset,
+					DeclaredBodySize: declared,
+					DeclaredCount:    int(count),
+					Game:             game,
+					KeySource:        keySource[:],
+					reader:           r,
+				}, nil
+			}
+		} else if count, err := readUint16(r); err != nil {
+			return nil, err
+		} else if declared, entries, err := readIndex(r, count, size, func(n int) int64 {
+			return 10 + 12*int64(n)
+		}); err != nil {
+			return nil, err
+		} else {
+			offset := 10 + 12*uint32(len(entries))
+			return &Reader{
+				Entries:          entries,
+				Flags:            flags,
+				BodySize:         bodySize(r.Size(), offset, flags, declared),
+				BodyOffset:       offset,
+				DeclaredBodySize: declared,
+				DeclaredCount:    int(count),
+				Game:             game,
+				reader:           r,
+			}, nil
+		}
+	}
+}
+
+// IndexByID returns the index of the entry with the given ID or -1 if there is none.
+func (mix *Reader) IndexByID(id uint32) int {
+	for i := 0; i < len(mix.Entries); i++ {
+		if mix.Entries[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// OpenFile returns a reader of the contents of the i-th entry.
+func (mix *Reader) OpenFile(i int) *io.SectionReader {
+	info := mix.Entries[i]
+	return io.NewSectionReader(mix.reader, int64(mix.BodyOffset+info.Offset), int64(info.Size))
+}
+
+// OpenMix opens the i-th entry as a nested mix file of the same game.
+// It returns an error if the entry does not have the shape of a mix file.
+func (mix *Reader) OpenMix(i int) (*Reader, error) {
+	r := mix.OpenFile(i)
+	sub, err := NewReader(r, r.Size(), mix.Game)
+	if err != nil {
+		return nil, err
+	} else if len(sub.Entries) == 0 || len(sub.Entries) != sub.DeclaredCount {
+		return nil, errNotMix
+	}
+	for _, entry := range sub.Entries {
+		if uint64(entry.Offset)+uint64(entry.Size) > uint64(sub.BodySize) {
+			return nil, errNotMix
+		}
+	}
+	sub.Game = mix.Game
+	return sub, nil
+}
+
+// ReadLmd names the entries using the local mix database if the mix file has one
+// and describes them using its descriptions entry if it has one.
+func (mix *Reader) ReadLmd() error {
+	descriptions := mix.readDescriptions()
+	for i := 0; i < len(mix.Entries); i++ {
+		if description, ok := descriptions[mix.Entries[i].ID]; ok {
+			mix.Entries[i].Description = description.Description
+		}
+	}
+
+	lmdID := LmdFileID(mix.Game)
+	if fileIndex := mix.IndexByID(lmdID); fileIndex == -1 {
+		return nil
+	} else if mapper, err := ReadLmd(mix.OpenFile(fileIndex)); err != nil {
+		return err
+	} else {
+		for i := 0; i < len(mix.Entries); i++ {
+			if name, ok := mapper[mix.Entries[i].ID]; ok {
+				mix.Entries[i].Name = name
+				mix.Entries[i].Source = SourceLmd
+			}
+		}
+		return nil
+	}
+}
+
+// ReadGmd names and describes the entries using a global mix database. See ReadGmdEntries.
+func (mix *Reader) ReadGmd(filename string) error {
+	entries, err := ReadGmdEntries(filename, mix.Game)
+	if err != nil {
+		return err
+	}
+	source := filename
+	if source == "" {
+		source = SourceEmbedded
+	}
+	fileID := GetFileID(mix.Game)
+	mapper := make(map[uint32]GmdEntry, len(entries))
+	for _, entry := range entries {
+		mapper[fileID(entry.Name)] = entry
+	}
+	for i := 0; i < len(mix.Entries); i++ {
+		if entry, ok := mapper[mix.Entries[i].ID]; ok {
+			mix.Entries[i].Name = entry.Name
+			mix.Entries[i].Description = entry.Description
+			mix.Entries[i].Source = source
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package mix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+func TestReaderRoundTrip(t *testing.T) {
+	for _, game := range []Game{GameCC1, GameRA1, GameCC2, GameRA2} {
+		var b bytes.Buffer
+		w := NewWriter(&b, game)
+		if game != GameCC1 {
+			w.Flags = FlagChecksum | FlagEncrypted
+		}
+		if files, err := ListFilesToPack("../test/files", true, game); err != nil {
+			t.Fatal(err)
+		} else {
+			w.Add(files...)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		mix, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), game)
+		if err != nil {
+			t.Fatal(game, err)
+		} else if err := mix.ReadLmd(); err != nil {
+			t.Fatal(game, err)
+		} else if len(mix.Entries) != 4 {
+			t.Fatal(game, len(mix.Entries))
+		}
+
+		for i, entry := range mix.Entries {
+			if entry.Name == "" {
+				t.Fatalf("%s: entry %08X is unnamed", game, entry.ID)
+			} else if entry.Name == LmdFilename {
+				continue
+			} else if expected, err := os.ReadFile(filepath.Join("../test/files", entry.Name)); err != nil {
+				t.Fatal(err)
+			} else if actual, err := io.ReadAll(mix.OpenFile(i)); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(expected, actual) {
+				t.Fatalf("%s: %s differs", game, entry.Name)
+			}
+		}
+	}
+}
+
+func TestReaderLyingCount(t *testing.T) {
+	for _, flags := range []uint32{0, FlagChecksum, FlagEncrypted} {
+		game := GameRA1
+		if flags == 0 {
+			game = GameCC1
+		}
+		files, err := ListFilesToPack("../test/files", true, game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := packBytes(t, game, flags, files...)
+		src, err := NewReader(bytes.NewReader(b), int64(len(b)), game)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case flags == 0:
+			binary.LittleEndian.PutUint16(b[0:], 0xFFFF)
+		case (flags & FlagEncrypted) != 0:
+			block, _ := blowfish.NewCipher(blowfishKeyFromKeySource(b[4:84]))
+			block.Decrypt(b[84:92], b[84:92])
+			binary.LittleEndian.PutUint16(b[84:], 0xFFFF)
+			block.Encrypt(b[84:92], b[84:92])
+		default:
+			binary.LittleEndian.PutUint16(b[4:], 0xFFFF)
+		}
+
+		mix, err := NewReader(bytes.NewReader(b), int64(len(b)), game)
+		if err != nil {
+			t.Fatalf("%x: %v", flags, err)
+		} else if mix.DeclaredCount != 0xFFFF || len(mix.Entries) != len(src.Entries) {
+			t.Fatalf("%x: expected %d entries but got %d of %d", flags, len(src.Entries), len(mix.Entries), mix.DeclaredCount)
+		} else if mix.BodyOffset != src.BodyOffset {
+			t.Fatalf("%x: body offset %d", flags, mix.BodyOffset)
+		}
+		for i := range src.Entries {
+			if mix.Entries[i] != src.Entries[i] {
+				t.Fatalf("%x: expected %+v but got %+v", flags, src.Entries[i], mix.Entries[i])
+			}
+		}
+
+		if problems, err := mix.Check(); err != nil {
+			t.Fatal(err)
+		} else if len(problems) != 1 || problems[0].Kind != ProblemCount {
+			t.Fatalf("%x: %+v", flags, problems)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package mix
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// sniffer recognizes the header of a file format at the start of b.
+// It returns the extension of the format and the size of the file,
+// or a size of 0 if the header does not determine it.
+type sniffer func(b []byte) (ext string, size int64, ok bool)
+
+// sniffers are ordered from the most to the least reliable.
+var sniffers = []sniffer{
+	sniffLmd,
+	sniffVqa,
+	sniffVxl,
+	sniffAud,
+	sniffShpTD,
+	sniffShpTS,
+	sniffHva,
+	sniffPcx,
+	sniffIni,
+}
+
+// sniffWindow is the number of bytes that the sniffers look at.
+const sniffWindow = 4096
+
+func sniff(b []byte) (string, int64, bool) {
+	for _, s := range sniffers {
+		if ext, size, ok := s(b); ok {
+			return ext, size, true
+		}
+	}
+	return "", 0, false
+}
+
+func u16(b []byte, i int) int64 {
+	return int64(binary.LittleEndian.Uint16(b[i:]))
+}
+
+func u32(b []byte, i int) int64 {
+	return int64(binary.LittleEndian.Uint32(b[i:]))
+}
+
+// sniffLmd recognizes a local mix database. Its size is the declared size.
+func sniffLmd(b []byte) (string, int64, bool) {
+	if len(b) < 52 || string(b[:32]) != lmdHeader {
+		return "", 0, false
+	}
+	return "dat", u32(b, 32), true
+}
+
+func sniffVqa(b []byte) (string, int64, bool) {
+	if len(b) < 12 || string(b[:4]) != "FORM" || string(b[8:12]) != "WVQA" {
+		return "", 0, false
+	}
+	return "vqa", 8 + int64(binary.BigEndian.Uint32(b[4:])), true
+}
+
+func sniffVxl(b []byte) (string, int64, bool) {
+	if len(b) < 32 || string(b[:16]) != "Voxel Animation\x00" {
+		return "", 0, false
+	}
+	limbs, tailers, body := u32(b, 20), u32(b, 24), u32(b, 28)
+	if limbs == 0 || limbs != tailers || limbs > 512 {
+		return "", 0, false
+	}
+	return "vxl", 802 + 28*limbs + body + 92*tailers, true
+}
+
+// sniffAud recognizes a Westwood audio file by its header and the marker of its first chunk.
+func sniffAud(b []byte) (string, int64, bool) {
+	if len(b) < 20 {
+		return "", 0, false
+	}
+	switch u16(b, 0) {
+	case 8000, 11025, 22050, 22100, 44100:
+	default:
+		return "", 0, false
+	}
+	size, flags, kind := u32(b, 2), b[10], b[11]
+	if size == 0 || flags > 3 || (kind != 1 && kind != 99) || u32(b, 16) != 0xDEAF {
+		return "", 0, false
+	}
+	return "aud", 12 + size, true
+}
+
+// sniffShpTD recognizes a cc1 or ra1 shape file. Its size is the offset that follows the last frame.
+func sniffShpTD(b []byte) (string, int64, bool) {
+	if len(b) < 14 {
+		return "", 0, false
+	}
+	count, w, h := u16(b, 0), u16(b, 6), u16(b, 8)
+	if count == 0 || w == 0 || h == 0 || w > 1024 || h > 1024 || len(b) < 14+8*int(count+2) {
+		return "", 0, false
+	}
+
+	prev := 14 + 8*(count+2)
+	if u32(b, 14)&0xFFFFFF != prev {
+		return "", 0, false
+	}
+	for i := int64(1); i <= count; i++ {
+		offset := u32(b, int(14+8*i)) & 0xFFFFFF
+		if offset < prev {
+			return "", 0, false
+		}
+		prev = offset
+	}
+	if u32(b, int(14+8*(count+1))) != 0 {
+		return "", 0, false
+	}
+	return "shp", prev, true
+}
+
+// sniffShpTS recognizes a cc2 or ra2 shape file by its frame headers.
+func sniffShpTS(b []byte) (string, int64, bool) {
+	if len(b) < 8+24 || u16(b, 0) != 0 {
+		return "", 0, false
+	}
+	w, h, count := u16(b, 2), u16(b, 4), u16(b, 6)
+	if w == 0 || h == 0 || w > 1024 || h > 1024 || count == 0 {
+		return "", 0, false
+	}
+
+	data := 8 + 24*count
+	first := int64(0)
+	for i := int64(0); i < count && int(8+24*i+24) <= len(b); i++ {
+		f := b[8+24*i:]
+		x, y, fw, fh, flags, offset := u16(f, 0), u16(f, 2), u16(f, 4), u16(f, 6), u32(f, 8), u32(f, 20)
+		if x+fw > w || y+fh > h || flags > 3 || (offset != 0 && offset < data) {
+			return "", 0, false
+		} else if offset != 0 && first == 0 {
+			first = offset
+		}
+	}
+	if first != data {
+		return "", 0, false
+	}
+	return "shp", 0, true
+}
+
+// sniffHva recognizes a voxel animation by its name and section names.
+func sniffHva(b []byte) (string, int64, bool) {
+	if len(b) < 24 || !isPaddedName(b[:16]) {
+		return "", 0, false
+	}
+	frames, sections := u32(b, 16), u32(b, 20)
+	if frames == 0 || frames > 10000 || sections == 0 || sections > 256 {
+		return "", 0, false
+	}
+	for i := int64(0); i < sections && int(24+16*i+16) <= len(b); i++ {
+		if !isPaddedName(b[24+16*i : 24+16*i+16]) {
+			return "", 0, false
+		}
+	}
+	return "hva", 24 + 16*sections + 48*frames*sections, true
+}
+
+// isPaddedName reports whether b is a printable name padded with zeros.
+func isPaddedName(b []byte) bool {
+	n := bytes.IndexByte(b, 0)
+	if n <= 0 {
+		return false
+	}
+	for _, c := range b[:n] {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	for _, c := range b[n:] {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sniffPcx(b []byte) (string, int64, bool) {
+	if len(b) < 128 || b[0] != 0x0A || b[2] != 1 || b[64] != 0 {
+		return "", 0, false
+	}
+	switch b[1] {
+	case 0, 2, 3, 4, 5:
+	default:
+		return "", 0, false
+	}
+	switch b[3] {
+	case 1, 2, 4, 8:
+	default:
+		return "", 0, false
+	}
+	switch b[65] {
+	case 1, 3, 4:
+	default:
+		return "", 0, false
+	}
+	xmin, ymin, xmax, ymax := u16(b, 4), u16(b, 6), u16(b, 8), u16(b, 10)
+	if xmax < xmin || ymax < ymin || xmax-xmin >= 4096 || ymax-ymin >= 4096 {
+		return "", 0, false
+	} else if u16(b, 66)*8 < (xmax-xmin+1)*int64(b[3]) {
+		return "", 0, false
+	}
+	return "pcx", 0, true
+}
+
+// sniffIni recognizes an ini file that starts with a section or a comment.
+// Its size is the length of the text, which is not determined here.
+func sniffIni(b []byte) (string, int64, bool) {
+	if len(b) < 3 || (b[0] != '[' && b[0] != ';') {
+		return "", 0, false
+	}
+	line := b
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		line = b[:i]
+	} else if len(b) == sniffWindow {
+		return "", 0, false
+	}
+	line = bytes.TrimRight(line, "\r")
+	if b[0] == '[' && (line[len(line)-1] != ']' || len(line) < 3) {
+		return "", 0, false
+	}
+	if textLength(b) < len(line) {
+		return "", 0, false
+	}
+	return "ini", 0, true
+}
+
+// textLength returns the number of leading bytes of b that are text.
+func textLength(b []byte) int {
+	for i, c := range b {
+		if (c < 0x20 || c > 0x7e) && c != '\t' && c != '\r' && c != '\n' {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// typeSniffer recognizes a file of size bytes by the start of its contents in b.
+type typeSniffer func(b []byte, size int64) (ext string, ok bool)
+
+// typeSniffers are ordered from the most to the least reliable.
+var typeSniffers = []typeSniffer{
+	sized(sniffLmd),
+	sized(sniffVqa),
+	sized(sniffVxl),
+	sized(sniffAud),
+	sniffCsf,
+	sniffWsa,
+	sniffFnt,
+	sniffCps,
+	sniffTmpTD,
+	sniffTmpTS,
+	sized(sniffShpTD),
+	sized(sniffShpTS),
+	sized(sniffHva),
+	sized(sniffPcx),
+	sniffPal,
+	sniffText,
+}
+
+// sized makes a typeSniffer of a sniffer that also checks that the file is not smaller than the format says.
+func sized(s sniffer) typeSniffer {
+	return func(b []byte, size int64) (string, bool) {
+		if ext, n, ok := s(b); ok && n <= size {
+			return ext, true
+		}
+		return "", false
+	}
+}
+
+// FileType returns the extension of the format of the i-th entry as recognized by its contents,
+// or an empty string if it is not recognized.
+// Recognized formats are mix files, local mix databases (dat), shp, pcx, pal, aud, vqa, wsa, cps,
+// fnt, tmp, vxl, hva, csf and ini.
+func (mix *Reader) FileType(i int) string {
+	if _, err := mix.OpenMix(i); err == nil {
+		return "mix"
+	}
+
+	r := mix.OpenFile(i)
+	b := make([]byte, min64(sniffWindow, r.Size()))
+	n, _ := r.ReadAt(b, 0)
+	for _, s := range typeSniffers {
+		if ext, ok := s(b[:n], r.Size()); ok {
+			return ext
+		}
+	}
+	return ""
+}
+
+// sniffCsf recognizes a string table.
+func sniffCsf(b []byte, size int64) (string, bool) {
+	if len(b) < 24 || string(b[:4]) != " FSC" || u32(b, 4) < 2 || u32(b, 4) > 3 {
+		return "", false
+	}
+	return "csf", true
+}
+
+// sniffWsa recognizes an animation by its frame offsets, the last of which is the end of the file.
+// The offsets do not count the palette that follows them if bit 0 of the flags is set.
+func sniffWsa(b []byte, size int64) (string, bool) {
+	if len(b) < 14 {
+		return "", false
+	}
+	frames, w, h := u16(b, 0), u16(b, 6), u16(b, 8)
+	if frames == 0 || w == 0 || h == 0 || w > 1024 || h > 1024 || len(b) < int(14+4*(frames+2)) {
+		return "", false
+	}
+
+	palette := int64(0)
+	if u16(b, 12)&1 != 0 {
+		palette = 768
+	}
+	prev := 14 + 4*(frames+2)
+	if u32(b, 14) != prev {
+		return "", false
+	}
+	for i := int64(1); i <= frames; i++ {
+		offset := u32(b, int(14+4*i))
+		if offset < prev {
+			return "", false
+		}
+		prev = offset
+	}
+	if last := u32(b, int(14+4*(frames+1))); last != 0 && last < prev {
+		return "", false
+	} else if last != 0 {
+		prev = last
+	}
+	if prev+palette != size {
+		return "", false
+	}
+	return "wsa", true
+}
+
+// sniffFnt recognizes a cc1 or ra1 font by its size and the offsets of its blocks.
+func sniffFnt(b []byte, size int64) (string, bool) {
+	if len(b) < 20 || u16(b, 0) != size || b[2] != 0 || b[3] != 5 || u16(b, 4) != 0x0e || u16(b, 6) != 0x14 {
+		return "", false
+	}
+	return "fnt", true
+}
+
+// sniffCps recognizes a compressed picture by its size, compression and palette size.
+func sniffCps(b []byte, size int64) (string, bool) {
+	if len(b) < 10 || u16(b, 0)+2 != size || u16(b, 2) > 4 {
+		return "", false
+	}
+	image, palette := u32(b, 4), u16(b, 8)
+	if image == 0 || image > 640*480 || (palette != 0 && palette != 768) {
+		return "", false
+	}
+	return "cps", true
+}
+
+// sniffTmpTD recognizes a cc1 or ra1 template of 24x24 tiles.
+func sniffTmpTD(b []byte, size int64) (string, bool) {
+	if len(b) < 32 || u16(b, 0) != 24 || u16(b, 2) != 24 || u16(b, 4) == 0 || u16(b, 4) > 128 || u16(b, 6) != 0 {
+		return "", false
+	} else if u32(b, 8) != size || u32(b, 12) > size || u32(b, 16) != 0 {
+		return "", false
+	}
+	return "tmp", true
+}
+
+// sniffTmpTS recognizes a cc2 or ra2 template by its tile size and the offsets of its tiles.
+func sniffTmpTS(b []byte, size int64) (string, bool) {
+	if len(b) < 16 {
+		return "", false
+	}
+	bx, by, w, h := u32(b, 0), u32(b, 4), u32(b, 8), u32(b, 12)
+	if !(w == 48 && h == 24) && !(w == 60 && h == 30) || bx == 0 || by == 0 || bx > 64 || by > 64 {
+		return "", false
+	}
+
+	count := bx * by
+	found := false
+	for i := int64(0); i < count && int(16+4*i+4) <= len(b); i++ {
+		offset := u32(b, int(16+4*i))
+		if offset != 0 && (offset < 16+4*count || offset >= size) {
+			return "", false
+		}
+		found = found || offset != 0
+	}
+	if !found {
+		return "", false
+	}
+	return "tmp", true
+}
+
+// sniffPal recognizes a palette of 256 colors with 6 bits per component.
+func sniffPal(b []byte, size int64) (string, bool) {
+	if size != 768 || len(b) != 768 {
+		return "", false
+	}
+	for _, c := range b {
+		if c > 63 {
+			return "", false
+		}
+	}
+	return "pal", true
+}
+
+// sniffText recognizes an ini file that is text throughout.
+func sniffText(b []byte, size int64) (string, bool) {
+	if _, _, ok := sniffIni(b); !ok || textLength(b) != len(b) {
+		return "", false
+	}
+	return "ini", true
+}
@@ -0,0 +1,69 @@
+package mix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFileType(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := packBytes(t, GameRA1, FlagEncrypted, files...)
+
+	pal := bytes.Repeat([]byte{63, 0, 32}, 256)
+
+	cps := make([]byte, 10+100)
+	binary.LittleEndian.PutUint16(cps[0:], uint16(len(cps)-2))
+	binary.LittleEndian.PutUint16(cps[2:], 4)
+	binary.LittleEndian.PutUint32(cps[4:], 64000)
+
+	csf := append([]byte(" FSC\x03\x00\x00\x00"), make([]byte, 16)...)
+
+	expected := map[string]string{
+		"inner.mix":  "mix",
+		"image.pcx":  "pcx",
+		"5tnk.shp":   "shp",
+		"rules.ini":  "ini",
+		"temp.pal":   "pal",
+		"title.cps":  "cps",
+		"ra2.csf":    "csf",
+		"random.bin": "",
+	}
+	outerFiles, err := ListFilesToPack("../test/files", false, GameRA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outerFiles = append(outerFiles,
+		&bufferFile{name: "inner.mix", buffer: *bytes.NewBuffer(inner)},
+		&bufferFile{name: "rules.ini", buffer: *bytes.NewBufferString("; rules\r\n[General]\r\nName=x\r\n")},
+		&bufferFile{name: "temp.pal", buffer: *bytes.NewBuffer(pal)},
+		&bufferFile{name: "title.cps", buffer: *bytes.NewBuffer(cps)},
+		&bufferFile{name: "ra2.csf", buffer: *bytes.NewBuffer(csf)},
+		&bufferFile{name: "random.bin", buffer: *bytes.NewBufferString("\x01\x02\x03\x04 not a known format")},
+	)
+	lmd, err := WriteLmd(GameRA1, outerFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix := packBuffer(t, GameRA1, 0, append(outerFiles, lmd)...)
+	if err := mix.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range mix.Entries {
+		ext, ok := expected[entry.Name]
+		if entry.Name == LmdFilename {
+			ext, ok = "dat", true
+		} else if entry.Name == "scenario.ini" {
+			ext, ok = "ini", true
+		}
+		if !ok {
+			t.Fatalf("unexpected entry %+v", entry)
+		} else if typ := mix.FileType(i); typ != ext {
+			t.Fatalf("%s: expected type %q but got %q", entry.Name, ext, typ)
+		}
+	}
+}
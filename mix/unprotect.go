@@ -0,0 +1,231 @@
+package mix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// lmdScan is the result of parsing a local mix database in the body.
+type lmdScan struct {
+	// ends are the offsets relative to the database where each valid name ends.
+	ends []uint32
+	// matched is the offset where the last name with an ID in the index ends.
+	matched uint32
+	// matches counts the names with an ID in the index.
+	matches int
+}
+
+// scanLmd parses the local mix database at offset in the body.
+// Names are read regardless of the declared size for as long as they look like file names.
+func (mix *Reader) scanLmd(offset uint32, ids map[uint32]bool) (lmdScan, bool) {
+	var scan lmdScan
+
+	if offset > mix.BodySize {
+		return scan, false
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(mix.reader, int64(mix.BodyOffset+offset), int64(mix.BodySize-offset)))
+
+	var hdr [52]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil || string(hdr[:32]) != lmdHeader {
+		return scan, false
+	}
+
+	fileID := GetFileID(mix.Game)
+	if game := Game(binary.LittleEndian.Uint32(hdr[44:])); game.String() != "" {
+		fileID = GetFileID(game)
+	}
+	count := binary.LittleEndian.Uint32(hdr[48:])
+
+	pos := uint32(len(hdr))
+	for n := uint32(0); n < count; n++ {
+		name, err := r.ReadSlice(0)
+		if err != nil || !isLmdName(name[:len(name)-1]) {
+			break
+		}
+		pos += uint32(len(name))
+		scan.ends = append(scan.ends, pos)
+		if ids[fileID(string(name[:len(name)-1]))] {
+			scan.matched = pos
+			scan.matches++
+		}
+	}
+
+	return scan, true
+}
+
+// size returns the size of the local mix database.
+// The declared size is trusted if it ends on a name and does not cut off a matched name.
+func (scan *lmdScan) size(declared uint32) uint32 {
+	if declared >= scan.matched {
+		for _, end := range scan.ends {
+			if end == declared {
+				return declared
+			}
+		}
+	}
+	return scan.matched
+}
+
+func isLmdName(name []byte) bool {
+	if len(name) == 0 || len(name) > 255 {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func (mix *Reader) entryIDs() map[uint32]bool {
+	ids := map[uint32]bool{}
+	for _, entry := range mix.Entries {
+		ids[entry.ID] = true
+	}
+	return ids
+}
+
+// lmdIntact reports whether the i-th entry is a complete local mix database.
+func (mix *Reader) lmdIntact(i int) bool {
+	entry := mix.Entries[i]
+	if uint64(entry.Offset)+uint64(entry.Size) > uint64(mix.BodySize) {
+		return false
+	} else if scan, ok := mix.scanLmd(entry.Offset, mix.entryIDs()); !ok || scan.matches == 0 {
+		return false
+	} else {
+		return scan.size(entry.Size) == entry.Size
+	}
+}
+
+// recoverLmdIndex searches the body for every local mix database header
+// and returns the offset and size of the one whose names match the most IDs in the index.
+func (mix *Reader) recoverLmdIndex() (uint32, uint32, bool) {
+	const chunkSize = 0x400000
+
+	buf := make([]byte, chunkSize+len(lmdHeader)-1)
+	ids := mix.entryIDs()
+
+	var bestOffset, bestSize uint32
+	bestMatches := 0
+
+	for base := uint32(0); base < mix.BodySize; base += chunkSize {
+		size := int64(mix.BodySize - base)
+		if size > int64(len(buf)) {
+			size = int64(len(buf))
+		}
+		n, err := mix.reader.ReadAt(buf[:size], int64(mix.BodyOffset+base))
+		if err != nil && err != io.EOF {
+			return 0, 0, false
+		}
+		for i := 0; ; i++ {
+			j := bytes.Index(buf[i:n], []byte(lmdHeader))
+			if j < 0 || i+j >= chunkSize {
+				break
+			}
+			i += j
+			offset := base + uint32(i)
+			if scan, ok := mix.scanLmd(offset, ids); ok && scan.matches > bestMatches {
+				var declared [4]byte
+				_, _ = mix.reader.ReadAt(declared[:], int64(mix.BodyOffset+offset+32))
+				bestOffset, bestSize, bestMatches = offset, scan.size(binary.LittleEndian.Uint32(declared[:])), scan.matches
+			}
+		}
+	}
+
+	return bestOffset, bestSize, bestMatches > 0
+}
+
+// RecoverLmd repairs entries that point beyond the body.
+// A local mix database that was moved, or whose size was changed, is searched for in the body.
+// Other entries that point beyond the body are emptied, and Rebuild drops them.
+// Entries that were cut short by the end of the file are left unchanged.
+func (mix *Reader) RecoverLmd() {
+	lmdID := LmdFileID(mix.Game)
+	for i, file := range mix.Entries {
+		if file.ID == lmdID && !mix.lmdIntact(i) {
+			if offset, size, found := mix.recoverLmdIndex(); found {
+				mix.Entries[i].Offset = offset
+				mix.Entries[i].Size = size
+				continue
+			}
+		}
+		if file.Offset > mix.BodySize && !mix.incomplete(file) {
+			mix.Entries[i].Offset = 0
+			mix.Entries[i].Size = 0
+			if mix.emptied == nil {
+				mix.emptied = map[uint32]bool{}
+			}
+			mix.emptied[file.ID] = true
+		}
+	}
+}
+
+// RewriteHeader writes the flags and the index to w in the header format of the mix file.
+func (mix *Reader) RewriteHeader(w io.Writer) error {
+	return writeHeader(w, mix.headerGame(), mix.Flags, mix.KeySource, mix.Entries, mix.BodySize)
+}
+
+// Rebuild writes a clean copy of the mix file to dst.
+// Entries that extend beyond the body, that were emptied by RecoverLmd or that repeat an earlier ID
+// are dropped and only the contents of the remaining entries are copied.
+// Entries with the same offset and size share a single copy of their contents.
+// If the mix file has a local mix database then it is rewritten with the names of the remaining entries.
+// The index is sorted, the body size and the checksum are recomputed
+// and the header format, flags and key source are kept.
+func (mix *Reader) Rebuild(dst io.Writer) error {
+	w := mix.newWriter(dst)
+	lmdID := LmdFileID(mix.Game)
+	lmdIndex := mix.IndexByID(lmdID)
+	aliases := mix.Aliases()
+
+	var lmd map[uint32]string
+	if lmdIndex != -1 {
+		lmd, _ = ReadLmd(mix.OpenFile(lmdIndex))
+	}
+
+	var names []string
+	count := 0
+	copies := map[int]uint32{}
+	seen := map[uint32]bool{}
+	for i, file := range mix.Entries {
+		if seen[file.ID] || (file.Size == 0 && mix.emptied[file.ID]) || uint64(file.Offset)+uint64(file.Size) > uint64(mix.BodySize) {
+			continue
+		}
+		seen[file.ID] = true
+		if lmdIndex != -1 && file.ID == lmdID {
+			continue
+		}
+
+		count++
+		if file.Name != "" {
+			names = append(names, file.Name)
+		} else if name, ok := lmd[file.ID]; ok {
+			names = append(names, name)
+		}
+
+		original := aliases[i].Original
+		if original == -1 {
+			original = i
+		}
+		if id, ok := copies[original]; ok {
+			w.Link(file.ID, file.Name, id)
+		} else {
+			copies[original] = file.ID
+			w.Copy(mix, i)
+		}
+	}
+
+	if lmdIndex != -1 {
+		if lmd, err := writeLmd(mix.Game, names, count); err != nil {
+			return err
+		} else {
+			w.Add(lmd)
+		}
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,127 @@
+package mix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := packBuffer(t, GameRA2, FlagChecksum, files...)
+	shp := src.IndexByID(FileIDV2("5tnk.shp"))
+
+	// mangle the index the way protected mix files do
+	src.Entries = append(src.Entries,
+		Entry{ID: 0xDEADBEEF, Offset: 0xFFFFFF00, Size: 16},
+		Entry{ID: src.Entries[0].ID, Offset: 0, Size: 4},
+		Entry{ID: 0x12345678, Offset: 0, Size: 0},
+		Entry{ID: 0xCAFEBABE, Offset: src.Entries[shp].Offset, Size: src.Entries[shp].Size},
+	)
+	for i := range src.Entries {
+		if src.Entries[i].ID == LmdFileID(GameRA2) {
+			src.Entries[i].Offset += 0x1000000
+		}
+	}
+	src.RecoverLmd()
+
+	var b bytes.Buffer
+	if err := src.Rebuild(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dst.Entries) != 6 {
+		t.Fatal(len(dst.Entries))
+	} else if v, err := dst.Verify(); err != nil {
+		t.Fatal(err)
+	} else if !v.OK() {
+		t.Fatalf("%+v", v)
+	} else if err := dst.ReadLmd(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the alias shares the bytes of the original and the empty entry is kept
+	if i, j := dst.IndexByID(FileIDV2("5tnk.shp")), dst.IndexByID(0xCAFEBABE); i == -1 || j == -1 {
+		t.Fatal("alias was dropped")
+	} else if dst.Entries[i].Offset != dst.Entries[j].Offset || dst.Entries[i].Size != dst.Entries[j].Size {
+		t.Fatal("alias was copied")
+	} else if i := dst.IndexByID(0x12345678); i == -1 || dst.Entries[i].Size != 0 {
+		t.Fatal("empty entry was dropped")
+	}
+
+	size := uint32(0)
+	for i, entry := range dst.Entries {
+		if entry.Name == "" && entry.ID != 0xCAFEBABE && entry.ID != 0x12345678 {
+			t.Fatalf("%08X is unnamed", entry.ID)
+		} else if i > 0 && int32(dst.Entries[i-1].ID) >= int32(entry.ID) {
+			t.Fatal("index is not sorted")
+		} else if entry.ID != 0xCAFEBABE {
+			size += entry.Size
+		}
+	}
+
+	if size != dst.DeclaredBodySize || binary.LittleEndian.Uint32(b.Bytes()[6:]) != size {
+		t.Fatal("unexpected body size")
+	}
+
+	// the local mix database counts the entries of the rebuilt mix file
+	lmd := dst.Entries[dst.IndexByID(LmdFileID(GameRA2))]
+	if count := binary.LittleEndian.Uint32(b.Bytes()[int(dst.BodyOffset+lmd.Offset)+48:]); count != uint32(len(dst.Entries)) {
+		t.Fatal(count)
+	}
+}
+
+func TestRecoverLmd(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", false, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a decoy database whose names are not in the index
+	var decoy bytes.Buffer
+	decoy.WriteString(lmdHeader)
+	for _, v := range []uint32{52 + 12, 0, 0, uint32(GameRA2), 1} {
+		_ = binary.Write(&decoy, binary.LittleEndian, v)
+	}
+	decoy.WriteString("nothere.shp\x00")
+	files = append(files, &bufferFile{name: "decoy.bin", buffer: decoy})
+
+	lmd, err := WriteLmd(GameRA2, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameRA2, 0, append(files, lmd)...)
+
+	for _, mangle := range []func(*Entry){
+		func(e *Entry) { e.Offset += 0x1000000 },
+		func(e *Entry) { e.Offset = 0 },
+		func(e *Entry) { e.Size = 60 },
+		func(e *Entry) { e.Size += 100 },
+	} {
+		mix, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := mix.IndexByID(LmdFileID(GameRA2))
+		expected := mix.Entries[i]
+		mangle(&mix.Entries[i])
+
+		mix.RecoverLmd()
+		if mix.Entries[i] != expected {
+			t.Fatalf("expected %+v but got %+v", expected, mix.Entries[i])
+		} else if err := mix.ReadLmd(); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range mix.Entries {
+			if entry.Name == "" {
+				t.Fatalf("%08X is unnamed", entry.ID)
+			}
+		}
+	}
+}
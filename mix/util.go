@@ -1,4 +1,4 @@
-package main
+package mix
 
 import (
 	"bytes"
@@ -20,7 +20,7 @@ func scanZStrings(data []byte, atEOF bool) (int, []byte, error) {
 
 func readUint16(r io.Reader) (uint16, error) {
 	b := [2]byte{}
-	if _, err := r.Read(b[:]); err != nil {
+	if _, err := io.ReadFull(r, b[:]); err != nil {
 		return 0, err
 	}
 	return binary.LittleEndian.Uint16(b[:]), nil
@@ -28,7 +28,7 @@ func readUint16(r io.Reader) (uint16, error) {
 
 func readUint32(r io.Reader) (uint32, error) {
 	b := [4]byte{}
-	if _, err := r.Read(b[:]); err != nil {
+	if _, err := io.ReadFull(r, b[:]); err != nil {
 		return 0, err
 	}
 	return binary.LittleEndian.Uint32(b[:]), nil
@@ -0,0 +1,100 @@
+package mix
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+)
+
+// Verification is the result of Reader.Verify.
+type Verification struct {
+	// Checksum is true if the mix file has the checksum flag.
+	Checksum bool
+	// Expected is the checksum that follows the body. It is nil if the checksum is missing.
+	Expected []byte
+	// Actual is the checksum computed over the body. It is nil if the mix file has no checksum flag.
+	Actual []byte
+	// Truncated is true if the file is shorter than the body size declared in the header.
+	Truncated bool
+	// Trailing is the number of bytes that follow the body and the checksum.
+	Trailing int64
+}
+
+// Missing reports whether the checksum flag is set but there is no checksum.
+func (v *Verification) Missing() bool {
+	return v.Checksum && v.Expected == nil
+}
+
+// Mismatch reports whether the checksum differs from the computed checksum.
+func (v *Verification) Mismatch() bool {
+	return v.Expected != nil && !bytes.Equal(v.Expected, v.Actual)
+}
+
+// OK reports whether the body and the checksum are intact and nothing follows them.
+func (v *Verification) OK() bool {
+	return !v.Truncated && !v.Missing() && !v.Mismatch() && v.Trailing == 0
+}
+
+// Verify checks the body against the body size declared in the header
+// and recomputes the checksum if the mix file has the checksum flag.
+func (mix *Reader) Verify() (*Verification, error) {
+	v := &Verification{
+		Checksum: (mix.Flags & FlagChecksum) != 0,
+	}
+
+	offset := int64(mix.BodyOffset)
+	declared := int64(mix.DeclaredBodySize)
+	rest := mix.reader.Size() - offset - declared
+	if rest < 0 {
+		v.Truncated = true
+		return v, nil
+	}
+
+	if v.Checksum {
+		h := sha1.New()
+		if _, err := io.Copy(h, io.NewSectionReader(mix.reader, offset, declared)); err != nil {
+			return nil, err
+		}
+		v.Actual = h.Sum(nil)
+
+		if rest >= sha1.Size {
+			v.Expected = make([]byte, sha1.Size)
+			if _, err := mix.reader.ReadAt(v.Expected, offset+declared); err != nil {
+				return nil, err
+			}
+			rest -= sha1.Size
+		}
+	}
+
+	v.Trailing = rest
+	return v, nil
+}
+
+// Incomplete returns the indices of the entries that end beyond the end of the file
+// but within the body size declared in the header, as in mix files that were cut short.
+// Some or none of the bytes of such entries are present.
+func (mix *Reader) Incomplete() []int {
+	var indices []int
+	for i, entry := range mix.Entries {
+		if mix.incomplete(entry) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func (mix *Reader) incomplete(entry Entry) bool {
+	end := uint64(entry.Offset) + uint64(entry.Size)
+	return end > uint64(mix.BodySize) && end <= uint64(mix.DeclaredBodySize)
+}
+
+// Available returns the number of bytes of the i-th entry that are present in the file.
+func (mix *Reader) Available(i int) uint32 {
+	entry := mix.Entries[i]
+	if entry.Offset >= mix.BodySize {
+		return 0
+	} else if rest := mix.BodySize - entry.Offset; rest < entry.Size {
+		return rest
+	}
+	return entry.Size
+}
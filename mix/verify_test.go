@@ -0,0 +1,83 @@
+package mix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameRA2, FlagChecksum|FlagEncrypted, files...)
+
+	verify := func(b []byte) *Verification {
+		mix, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := mix.Verify()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if v := verify(b); !v.OK() {
+		t.Fatalf("%+v", v)
+	}
+
+	corrupt := append([]byte{}, b...)
+	corrupt[len(corrupt)-100] ^= 0xFF
+	if v := verify(corrupt); !v.Mismatch() || v.OK() {
+		t.Fatalf("%+v", v)
+	}
+
+	if v := verify(b[:len(b)-20]); !v.Missing() || v.OK() {
+		t.Fatalf("%+v", v)
+	}
+
+	if v := verify(append(append([]byte{}, b...), 1, 2, 3)); v.Trailing != 3 || v.Mismatch() {
+		t.Fatalf("%+v", v)
+	}
+
+	if v := verify(b[:len(b)-40]); !v.Truncated {
+		t.Fatalf("%+v", v)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	files, err := ListFilesToPack("../test/files", true, GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := packBytes(t, GameRA2, FlagChecksum, files...)
+	src, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cut the file in the middle of the entry at the end of the body
+	last := 0
+	for i, entry := range src.Entries {
+		if entry.Offset > src.Entries[last].Offset {
+			last = i
+		}
+	}
+	entry := src.Entries[last]
+	cut := int(src.BodyOffset+entry.Offset) + 10
+
+	mix, err := NewReader(bytes.NewReader(b[:cut]), int64(cut), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if incomplete := mix.Incomplete(); len(incomplete) != 1 || incomplete[0] != last {
+		t.Fatalf("%v", incomplete)
+	} else if n := mix.Available(last); n != 10 {
+		t.Fatalf("expected 10 bytes but got %d", n)
+	}
+
+	if incomplete := src.Incomplete(); len(incomplete) != 0 {
+		t.Fatalf("%v", incomplete)
+	}
+}
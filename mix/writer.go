@@ -0,0 +1,440 @@
+package mix
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+const (
+	FlagChecksum  uint32 = 0x00010000
+	FlagEncrypted uint32 = 0x00020000
+)
+
+func writeIndex(w io.Writer, entries []Entry, bodySize uint32) error {
+	buf := make([]byte, 6+12*len(entries))
+	binary.LittleEndian.PutUint16(buf[0:], uint16(len(entries)))
+	binary.LittleEndian.PutUint32(buf[2:], bodySize)
+	for i, entry := range entries {
+		binary.LittleEndian.PutUint32(buf[6+12*i+0:], entry.ID)
+		binary.LittleEndian.PutUint32(buf[6+12*i+4:], entry.Offset)
+		binary.LittleEndian.PutUint32(buf[6+12*i+8:], entry.Size)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func writeHeader(w io.Writer, game Game, flags uint32, keySource []byte, entries []Entry, bodySize uint32) error {
+	if game != GameCC1 {
+		if _, err := writeUint32(w, flags); err != nil {
+			return err
+		}
+	} else if flags != 0 {
+		return errors.New("game cc1 does not support flags")
+	}
+
+	if (flags & FlagEncrypted) != 0 {
+		if _, err := w.Write(keySource); err != nil {
+			return err
+		}
+
+		blowfishKey := blowfishKeyFromKeySource(keySource)
+		cipher, err := blowfish.NewCipher(blowfishKey)
+		if err != nil {
+			return err
+		}
+		e := newEcbWriter(w, cipher)
+		if err := writeIndex(e, entries, bodySize); err != nil {
+			return err
+		}
+		return e.Flush()
+	}
+
+	return writeIndex(w, entries, bodySize)
+}
+
+func headerSize(game Game, flags uint32, count int) int64 {
+	if game == GameCC1 {
+		return 6 + 12*int64(count)
+	} else if (flags & FlagEncrypted) != 0 {
+		return 84 + ((6 + 12*int64(count) + 7) &^ 7)
+	}
+	return 10 + 12*int64(count)
+}
+
+type writerEntry struct {
+	Entry
+	file File
+	// linked is true if the entry shares the contents of the entry whose ID is original.
+	linked   bool
+	original uint32
+}
+
+type entriesByID []writerEntry
+
+func (xs entriesByID) Len() int {
+	return len(xs)
+}
+
+func (xs entriesByID) Less(i, j int) bool {
+	return int32(xs[i].ID) < int32(xs[j].ID)
+}
+
+func (xs entriesByID) Swap(i, j int) {
+	xs[i], xs[j] = xs[j], xs[i]
+}
+
+type spool interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Writer writes a mix file.
+//
+// Entries are either added as Files, which are read when the Writer is closed,
+// or written through the writers returned by Create and CreateID.
+// Entries added by Link share the contents of another entry.
+// Written entries are spooled to the destination if it implements io.ReaderAt and io.WriterAt
+// and can be read from, and to a temporary file otherwise.
+// The index and the checksum are written when the Writer is closed.
+type Writer struct {
+	// Flags is a combination of FlagChecksum and FlagEncrypted.
+	Flags uint32
+	// KeySource is used to encrypt the index.
+	// A random key source is generated on Close if it is nil.
+	KeySource []byte
+
+	writer    io.Writer
+	game      Game
+	entries   []writerEntry
+	spool     spool
+	spoolSize int64
+	tempFile  *os.File
+	current   *entryWriter
+}
+
+// NewWriter returns a Writer that writes a mix file for game to w.
+// If w implements io.ReaderAt and io.WriterAt and can be read from then the mix file is written at offset 0.
+func NewWriter(w io.Writer, game Game) *Writer {
+	return &Writer{
+		writer: w,
+		game:   game,
+	}
+}
+
+// Add adds files to the mix file. The files are read when the Writer is closed.
+func (w *Writer) Add(files ...File) {
+	fileID := GetFileID(w.game)
+	for _, f := range files {
+		w.entries = append(w.entries, writerEntry{
+			Entry: Entry{
+				ID:   fileID(f.Name()),
+				Size: uint32(f.Size()),
+				Name: f.Name(),
+			},
+			file: f,
+		})
+	}
+}
+
+// Copy adds the i-th entry of mix to the mix file keeping its ID and name.
+// The contents are read when the Writer is closed.
+func (w *Writer) Copy(mix *Reader, i int) {
+	entry := mix.Entries[i]
+	w.entries = append(w.entries, writerEntry{
+		Entry: Entry{
+			ID:   entry.ID,
+			Size: entry.Size,
+			Name: entry.Name,
+		},
+		file: &sectionFile{
+			name:    entry.Name,
+			section: mix.OpenFile(i),
+		},
+	})
+}
+
+// Link adds an entry with the given ID and name that shares the contents of the entry whose ID is original.
+// Its offset and size are those of the original entry and its contents are not written again.
+func (w *Writer) Link(id uint32, name string, original uint32) {
+	w.entries = append(w.entries, writerEntry{
+		Entry: Entry{
+			ID:   id,
+			Name: name,
+		},
+		linked:   true,
+		original: original,
+	})
+}
+
+// Create adds an entry named name to the mix file and returns a writer of its contents.
+// The writer is valid until the next call to Create, CreateID or Close.
+func (w *Writer) Create(name string) (io.Writer, error) {
+	return w.create(GetFileID(w.game)(name), name)
+}
+
+// CreateID adds an entry with the given ID to the mix file and returns a writer of its contents.
+// The writer is valid until the next call to Create, CreateID or Close.
+func (w *Writer) CreateID(id uint32) (io.Writer, error) {
+	return w.create(id, "")
+}
+
+func (w *Writer) create(id uint32, name string) (io.Writer, error) {
+	if w.current != nil {
+		w.current.closed = true
+		w.current = nil
+	}
+
+	if w.spool == nil {
+		if s, ok := w.writer.(spool); ok && canReadAt(s) {
+			w.spool = s
+		} else if f, err := os.CreateTemp("", "ccmixar-*.tmp"); err != nil {
+			return nil, err
+		} else {
+			w.spool = f
+			w.tempFile = f
+		}
+	}
+
+	w.entries = append(w.entries, writerEntry{
+		Entry: Entry{
+			ID:     id,
+			Offset: uint32(w.spoolSize),
+			Name:   name,
+		},
+	})
+
+	w.current = &entryWriter{
+		writer: w,
+		index:  len(w.entries) - 1,
+	}
+	return w.current, nil
+}
+
+type entryWriter struct {
+	writer *Writer
+	index  int
+	closed bool
+}
+
+func (ew *entryWriter) Write(p []byte) (int, error) {
+	w := ew.writer
+	if ew.closed {
+		return 0, errors.New("write to closed entry")
+	} else if w.spoolSize+int64(len(p)) > 0xFFFFFFFF {
+		return 0, errors.New("mix file too large")
+	}
+	n, err := w.spool.WriteAt(p, w.spoolSize)
+	w.spoolSize += int64(n)
+	w.entries[ew.index].Size += uint32(n)
+	return n, err
+}
+
+// Close writes the index, the body and the checksum of the mix file.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.current != nil {
+		w.current.closed = true
+		w.current = nil
+	}
+
+	if w.tempFile != nil {
+		defer os.Remove(w.tempFile.Name())
+		defer w.tempFile.Close()
+	}
+
+	if w.KeySource == nil && (w.Flags&FlagEncrypted) != 0 {
+		if ks, err := NewKeySource(rand.Reader); err != nil {
+			return err
+		} else {
+			w.KeySource = ks
+		}
+	}
+	keySource := w.KeySource
+
+	if len(w.entries) > 0xFFFF {
+		return fmt.Errorf("too many files: %d", len(w.entries))
+	}
+
+	sort.Stable(entriesByID(w.entries))
+
+	for i := 1; i < len(w.entries); i++ {
+		if a, b := w.entries[i-1], w.entries[i]; a.ID == b.ID {
+			return fmt.Errorf("ID collision %x on %s and %s", a.ID, b.Name, a.Name)
+		}
+	}
+
+	if w.spool != nil && w.tempFile == nil {
+		return w.closeInPlace(keySource)
+	}
+
+	entries := make([]Entry, len(w.entries))
+	bodySize := int64(0)
+	for i, e := range w.entries {
+		entries[i] = e.Entry
+		if !e.linked {
+			entries[i].Offset = uint32(bodySize)
+			bodySize += int64(e.Size)
+		}
+	}
+
+	if bodySize > 0xFFFFFFFF {
+		return errors.New("mix file too large")
+	} else if err := w.resolveLinks(entries); err != nil {
+		return err
+	} else if err := writeHeader(w.writer, w.game, w.Flags, keySource, entries, uint32(bodySize)); err != nil {
+		return err
+	}
+
+	body := w.writer
+	h := sha1.New()
+	if (w.Flags & FlagChecksum) != 0 {
+		body = io.MultiWriter(w.writer, h)
+	}
+
+	for _, e := range w.entries {
+		if e.linked {
+			continue
+		} else if err := w.copyEntry(body, e); err != nil {
+			return err
+		}
+	}
+
+	if (w.Flags & FlagChecksum) != 0 {
+		if _, err := w.writer.Write(h.Sum(nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveLinks gives the linked entries the offsets and sizes of the entries that they share the contents of.
+func (w *Writer) resolveLinks(entries []Entry) error {
+	originals := map[uint32]int{}
+	for i, e := range w.entries {
+		if !e.linked {
+			originals[e.ID] = i
+		}
+	}
+	for i, e := range w.entries {
+		if !e.linked {
+			continue
+		} else if j, ok := originals[e.original]; !ok {
+			return fmt.Errorf("%x: no entry %x to link to", e.ID, e.original)
+		} else {
+			entries[i].Offset = entries[j].Offset
+			entries[i].Size = entries[j].Size
+		}
+	}
+	return nil
+}
+
+func (w *Writer) copyEntry(dst io.Writer, e writerEntry) error {
+	if e.file == nil {
+		_, err := io.Copy(dst, io.NewSectionReader(w.spool, int64(e.Offset), int64(e.Size)))
+		return err
+	} else if f, err := e.file.Open(); err != nil {
+		return err
+	} else {
+		defer f.Close()
+		if n, err := io.Copy(dst, f); err != nil {
+			return err
+		} else if n != int64(e.Size) {
+			return fmt.Errorf("%s: expected %d bytes but read %d", e.Name, e.Size, n)
+		}
+		return nil
+	}
+}
+
+// closeInPlace finishes a mix file whose body was spooled to the destination.
+// The body is moved forward to make room for the header.
+func (w *Writer) closeInPlace(keySource []byte) error {
+	for i, e := range w.entries {
+		if e.file != nil {
+			ew := &offsetWriter{w.spool, w.spoolSize}
+			if err := w.copyEntry(ew, e); err != nil {
+				return err
+			}
+			w.entries[i].Offset = uint32(w.spoolSize)
+			w.spoolSize = ew.offset
+		}
+	}
+
+	if w.spoolSize > 0xFFFFFFFF {
+		return errors.New("mix file too large")
+	}
+
+	entries := make([]Entry, len(w.entries))
+	for i, e := range w.entries {
+		entries[i] = e.Entry
+	}
+	if err := w.resolveLinks(entries); err != nil {
+		return err
+	}
+
+	hdrSize := headerSize(w.game, w.Flags, len(entries))
+	if err := moveForward(w.spool, w.spoolSize, hdrSize); err != nil {
+		return err
+	}
+
+	hdr := &offsetWriter{w.spool, 0}
+	if err := writeHeader(hdr, w.game, w.Flags, keySource, entries, uint32(w.spoolSize)); err != nil {
+		return err
+	}
+
+	if (w.Flags & FlagChecksum) != 0 {
+		h := sha1.New()
+		if _, err := io.Copy(h, io.NewSectionReader(w.spool, hdrSize, w.spoolSize)); err != nil {
+			return err
+		} else if _, err := w.spool.WriteAt(h.Sum(nil), hdrSize+w.spoolSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveForward moves the first size bytes of s forward by n bytes.
+func moveForward(s spool, size, n int64) error {
+	buf := make([]byte, 1<<20)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		b := buf[:end-start]
+		if _, err := s.ReadAt(b, start); err != nil {
+			return err
+		} else if _, err := s.WriteAt(b, start+n); err != nil {
+			return err
+		}
+		end = start
+	}
+	return nil
+}
+
+// canReadAt reports whether s can be read from, which a file that was opened write-only cannot.
+func canReadAt(s spool) bool {
+	var b [1]byte
+	_, err := s.ReadAt(b[:], 0)
+	return err == nil || err == io.EOF
+}
+
+type offsetWriter struct {
+	writer io.WriterAt
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
@@ -0,0 +1,160 @@
+package mix
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func pack(w io.Writer, files []File, game Game, flags uint32, keySource []byte) error {
+	mw := NewWriter(w, game)
+	mw.Flags = flags
+	mw.KeySource = keySource
+	mw.Add(files...)
+	return mw.Close()
+}
+
+func TestPackCC1(t *testing.T) {
+	if f, err := os.OpenFile("../test/cc1.mix", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		t.Fatal(err)
+	} else if files, err := ListFilesToPack("../test/files", true, GameCC1); err != nil {
+		t.Fatal(err)
+	} else if err := pack(f, files, GameCC1, 0, nil); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackRA1(t *testing.T) {
+	keySource := []byte{
+		0xca, 0xd0, 0xb0, 0x1b, 0xfe, 0x3f, 0x3f, 0xb6,
+		0xca, 0xc0, 0xbd, 0x8f, 0x40, 0xf0, 0xee, 0x85,
+		0x6e, 0xe1, 0xda, 0x7a, 0xef, 0xb4, 0xd4, 0xbb,
+		0x6a, 0xd8, 0x4b, 0x84, 0x26, 0x99, 0x6f, 0xfd,
+		0x65, 0x97, 0xf2, 0x5f, 0xa4, 0x46, 0xdb, 0x47,
+		0x88, 0x63, 0x4f, 0x2c, 0x14, 0x0b, 0x3c, 0xce,
+		0xaa, 0xc4, 0x5c, 0xe4, 0x15, 0x86, 0x26, 0x5c,
+		0x52, 0x3a, 0x80, 0xf8, 0xbe, 0x45, 0x40, 0x6a,
+		0x66, 0xb4, 0xc5, 0xf6, 0xd0, 0x12, 0xe0, 0x43,
+		0x44, 0x65, 0xc6, 0xe3, 0x9e, 0xf9, 0x43, 0x35,
+	}
+
+	if f, err := os.OpenFile("../test/ra1.mix", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); err != nil {
+		t.Fatal(err)
+	} else if files, err := ListFilesToPack("../test/files", true, GameRA1); err != nil {
+		t.Fatal(err)
+	} else if err := pack(f, files, GameRA1, FlagChecksum|FlagEncrypted, keySource); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testWriterCreate(t *testing.T, dst io.Writer, read func() []byte) {
+	w := NewWriter(dst, GameRA2)
+	w.Flags = FlagChecksum | FlagEncrypted
+
+	contents := map[uint32]string{
+		FileIDV2("rules.ini"): "[General]\n",
+		0xCAFEBABE:            "unnamed",
+		FileIDV2("5tnk.shp"):  "",
+		0x0BADF00D:            "[General]\n",
+	}
+
+	if fw, err := w.Create("rules.ini"); err != nil {
+		t.Fatal(err)
+	} else if _, err := io.WriteString(fw, "[General]\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := ReadDir("../test/files"); err != nil {
+		t.Fatal(err)
+	} else {
+		for _, f := range files {
+			if f.Name() == "5tnk.shp" {
+				w.Add(f)
+				b, _ := os.ReadFile(filepath.Join("../test/files", f.Name()))
+				contents[FileIDV2(f.Name())] = string(b)
+			}
+		}
+	}
+
+	if fw, err := w.CreateID(0xCAFEBABE); err != nil {
+		t.Fatal(err)
+	} else if _, err := io.WriteString(fw, "unnamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Link(0x0BADF00D, "", FileIDV2("rules.ini"))
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := read()
+	mix, err := NewReader(bytes.NewReader(b), int64(len(b)), GameRA2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(mix.Entries) != len(contents) {
+		t.Fatal(len(mix.Entries))
+	}
+
+	for i, entry := range mix.Entries {
+		if i > 0 && int32(mix.Entries[i-1].ID) >= int32(entry.ID) {
+			t.Fatal("index is not sorted")
+		} else if actual, err := io.ReadAll(mix.OpenFile(i)); err != nil {
+			t.Fatal(err)
+		} else if string(actual) != contents[entry.ID] {
+			t.Fatalf("%08X: unexpected contents", entry.ID)
+		}
+	}
+
+	if i, j := mix.IndexByID(0x0BADF00D), mix.IndexByID(FileIDV2("rules.ini")); mix.Entries[i].Offset != mix.Entries[j].Offset {
+		t.Fatal("linked entry does not share the contents")
+	}
+
+	body := b[mix.BodyOffset : len(b)-sha1.Size]
+	if sum := sha1.Sum(body); !bytes.Equal(sum[:], b[len(b)-sha1.Size:]) {
+		t.Fatal("checksum mismatch")
+	}
+}
+
+func TestWriterCreateTempFile(t *testing.T) {
+	var b bytes.Buffer
+	testWriterCreate(t, &b, b.Bytes)
+}
+
+func TestWriterCreateInPlace(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.mix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	testWriterCreate(t, f, func() []byte {
+		b, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestWriterCreateWriteOnly(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "out.mix")
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	testWriterCreate(t, f, func() []byte {
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
@@ -1,11 +0,0 @@
-package main
-
-// func Test_unp(t *testing.T) {
-// 	if f, err := os.Open("test/ccmixcc1.mix"); err != nil {
-// 		t.Fatal(err)
-// 	} else if mix, err := readMixFile(f); err != nil {
-// 		t.Fatal(err)
-// 	} else {
-// 		t.Fatal(mix)
-// 	}
-// }